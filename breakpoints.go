@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// showBreakpointsDialog List, add, toggle & remove the breakpoints of the emulated machine
+func showBreakpointsDialog(w fyne.Window, emu *runner) {
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		var breakpoints []emulator.Breakpoint
+		emu.Do(func(dmg *emulator.DMG) {
+			breakpoints = dmg.Breakpoints()
+		})
+		list.RemoveAll()
+		if len(breakpoints) == 0 {
+			list.Add(widget.NewLabel("No breakpoint."))
+		}
+		for _, b := range breakpoints {
+			check := widget.NewCheck(b.String(), nil)
+			check.SetChecked(!b.Disabled)
+			check.OnChanged = func(enabled bool) {
+				emu.Do(func(dmg *emulator.DMG) {
+					dmg.SetBreakpointEnabled(b.ID, enabled)
+				})
+			}
+			remove := widget.NewButton("Remove", func() {
+				emu.Do(func(dmg *emulator.DMG) {
+					dmg.RemoveBreakpoint(b.ID)
+				})
+				refresh()
+			})
+			list.Add(container.NewBorder(nil, nil, nil, remove, check))
+		}
+	}
+	refresh()
+
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("0x0150, 1:0x4000, w 0xC000-0xC0FF=0x03, op 0x40")
+	add := widget.NewButton("Add", func() {
+		b, err := emulator.ParseBreakpoint(entry.Text)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		emu.Do(func(dmg *emulator.DMG) {
+			dmg.AddBreakpoint(b)
+		})
+		entry.SetText("")
+		refresh()
+	})
+	entry.OnSubmitted = func(string) {
+		add.OnTapped()
+	}
+
+	form := container.NewBorder(nil, nil, nil, add, entry)
+	content := container.NewBorder(nil, form, nil, nil, container.NewVScroll(list))
+	d := dialog.NewCustom("Breakpoints", "Close", content, w)
+	d.Resize(fyne.NewSize(480, 360))
+	d.Show()
+}
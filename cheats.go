@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// cheatExtension Extension of the cheat file saved next to each ROM
+const cheatExtension = ".cht"
+
+// cheatMenu "Cheats..." item : list, add & toggle the cheats of the loaded ROM, saved to its cheat file on every change
+type cheatMenu struct {
+	window fyne.Window
+	emu    *runner
+	cheats *fyne.MenuItem
+	onMenu func() // Called when menu items need a refresh
+}
+
+// newCheatMenu Create the menu item, disabled until a ROM is loaded
+func newCheatMenu(w fyne.Window, emu *runner) *cheatMenu {
+	m := &cheatMenu{window: w, emu: emu}
+	m.cheats = fyne.NewMenuItem("Cheats...", m.showDialog)
+	m.cheats.Disabled = true
+	return m
+}
+
+// item Get the menu item
+func (m *cheatMenu) item() *fyne.MenuItem {
+	return m.cheats
+}
+
+// load Apply the cheats saved for the ROM loaded in dmg, called with exclusive access to the DMG
+func (m *cheatMenu) load(dmg *emulator.DMG) error {
+	cheats, err := readCheatFile(dmg.SavePath(cheatExtension))
+	dmg.SetCheats(cheats)
+	return err
+}
+
+// SetEnabled Enable editing cheats, once a ROM is loaded
+func (m *cheatMenu) SetEnabled(enabled bool) {
+	m.cheats.Disabled = !enabled
+	if m.onMenu != nil {
+		m.onMenu()
+	}
+}
+
+// update Apply cheats & save them next to the ROM
+func (m *cheatMenu) update(cheats []emulator.Cheat) {
+	var path string
+	m.emu.Do(func(dmg *emulator.DMG) {
+		dmg.SetCheats(cheats)
+		path = dmg.SavePath(cheatExtension)
+	})
+	if err := writeCheatFile(cheats, path); err != nil {
+		dialog.ShowError(fmt.Errorf("error saving cheats %s: %w", path, err), m.window)
+	}
+}
+
+// add Enable new cheats for the loaded ROM
+func (m *cheatMenu) add(cheats ...emulator.Cheat) {
+	var current []emulator.Cheat
+	m.emu.Do(func(dmg *emulator.DMG) {
+		current = dmg.Cheats()
+	})
+	m.update(append(current, cheats...))
+}
+
+// showDialog Show the cheat list
+func (m *cheatMenu) showDialog() {
+	var cheats []emulator.Cheat
+	m.emu.Do(func(dmg *emulator.DMG) {
+		cheats = dmg.Cheats()
+	})
+
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.RemoveAll()
+		if len(cheats) == 0 {
+			list.Add(widget.NewLabel("No cheat for this ROM yet."))
+		}
+		for i, c := range cheats {
+			label := c.Code
+			if c.Name != "" {
+				label = c.Name + " (" + c.Code + ")"
+			}
+			check := widget.NewCheck(label, nil)
+			check.SetChecked(c.Enabled)
+			check.OnChanged = func(enabled bool) {
+				cheats[i].Enabled = enabled
+				m.update(cheats)
+			}
+			remove := widget.NewButton("Remove", func() {
+				cheats = append(cheats[:i:i], cheats[i+1:]...)
+				m.update(cheats)
+				refresh()
+			})
+			list.Add(container.NewBorder(nil, nil, nil, remove, check))
+		}
+	}
+	refresh()
+
+	code := widget.NewEntry()
+	code.SetPlaceHolder("ABC-DEF-GHI or 01VVLLHH")
+	name := widget.NewEntry()
+	name.SetPlaceHolder("Name")
+	add := widget.NewButton("Add", func() {
+		c, err := emulator.ParseCheat(code.Text)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		c.Name = name.Text
+		c.Enabled = true
+		cheats = append(cheats, c)
+		m.update(cheats)
+		code.SetText("")
+		name.SetText("")
+		refresh()
+	})
+
+	form := container.NewBorder(nil, nil, nil, add, container.NewGridWithColumns(2, code, name))
+	content := container.NewBorder(nil, form, nil, nil, container.NewVScroll(list))
+	d := dialog.NewCustom("Cheats", "Close", content, m.window)
+	d.Resize(fyne.NewSize(480, 360))
+	d.Show()
+}
+
+// readCheatFile Read the cheats saved at path, none being saved yet isn't an error
+func readCheatFile(path string) ([]emulator.Cheat, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return emulator.ReadCheats(f)
+}
+
+// writeCheatFile Save cheats to path
+func writeCheatFile(cheats []emulator.Cheat, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := emulator.WriteCheats(f, cheats); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
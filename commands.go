@@ -0,0 +1,463 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"khopa.github.io/gogbemulator/emulator"
+	"khopa.github.io/gogbemulator/headless"
+)
+
+// infiniteLoopRepeats Times a jump to itself must repeat before the program is considered stuck
+const infiniteLoopRepeats = 100
+
+// machineFlags Options describing the emulated hardware, shared by the commands running ROMs
+type machineFlags struct {
+	bootROM string
+	model   string
+	palette string
+	patch   string
+}
+
+func (m *machineFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.bootROM, "bootrom", "", "optional boot ROM to run before the cartridge (skipped when empty)")
+	fs.StringVar(&m.model, "model", "DMG", "hardware model to emulate (DMG, DMG0, MGB, SGB, SGB2, CGB, AGB)")
+	fs.StringVar(&m.palette, "palette", "", "color scheme: a built-in one (\"Pocket Grey\", \"CGB Left\"...) or a scheme file (model colors when empty)")
+	fs.StringVar(&m.patch, "patch", "", "IPS, UPS or BPS patch applied to the ROM in memory (the one named after the ROM when empty, none to disable)")
+}
+
+// newDMG Create the emulator described by the flags, with romPath loaded if not empty
+func (m *machineFlags) newDMG(romPath string) (*emulator.DMG, int) {
+	model, err := emulator.ParseModel(m.model)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitUsage
+	}
+	dmg := emulator.MakeDMGWithOptions(emulator.Options{Model: model})
+	if m.palette != "" {
+		s, err := resolveColorScheme(m.palette)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading palette: %v\n", err)
+			return nil, exitError
+		}
+		dmg.SetColorScheme(s)
+	}
+	if m.bootROM != "" {
+		if err := dmg.LoadBootROM(m.bootROM); err != nil {
+			fmt.Fprintf(os.Stderr, "error loading boot ROM: %v\n", err)
+			return nil, exitError
+		}
+	}
+	if romPath != "" {
+		rom, err := emulator.ReadROM(romPath)
+		if err == nil {
+			rom, err = emulator.PatchROM(rom, m.patch)
+		}
+		if err == nil {
+			err = dmg.LoadROMFile(rom)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading ROM %s: %v\n", romPath, err)
+			return nil, exitError
+		}
+		dmg.Reset()
+	}
+	return dmg, exitOK
+}
+
+func runCommand(name string, args []string) int {
+	var machine machineFlags
+	fs := newFlagSet(name)
+	machine.register(fs)
+	rewindInterval := fs.Int("rewind-interval", emulator.DefaultRewindInterval, "frames between two rewind snapshots")
+	rewindBudget := fs.Int("rewind-budget", emulator.DefaultRewindBudget>>20, "memory used by rewind snapshots, in MB")
+	speed := fs.String("speed", "", "emulation speed, from 0.25x to 16x, or unlimited (last used one when empty)")
+	turboSpeed := fs.String("turbo-speed", "", "speed while the turbo key (Tab) is held (last used one when empty)")
+	positional, code, ok := parseArgs(fs, args, 0, 1)
+	if !ok {
+		return code
+	}
+
+	options := guiOptions{rewindInterval: *rewindInterval, restorePalette: machine.palette == ""}
+	if options.speed, ok = parseSpeedFlag(*speed); !ok {
+		return exitUsage
+	}
+	if options.turboSpeed, ok = parseSpeedFlag(*turboSpeed); !ok {
+		return exitUsage
+	}
+
+	// The ROM is loaded by the window, so that errors show up there
+	dmg, code := machine.newDMG("")
+	if dmg == nil {
+		return code
+	}
+	dmg.EnableRewind(emulator.RewindOptions{Interval: *rewindInterval, Budget: *rewindBudget << 20})
+
+	if len(positional) > 0 {
+		options.romPath = positional[0]
+		options.patchPath = machine.patch
+	}
+	runGUI(dmg, options)
+	return exitOK
+}
+
+// parseSpeedFlag Parse an optional speed, nil when not given
+func parseSpeedFlag(text string) (*emulator.Speed, bool) {
+	if text == "" {
+		return nil, true
+	}
+	s, err := emulator.ParseSpeed(text)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	return &s, true
+}
+
+func headlessCommand(name string, args []string) int {
+	var machine machineFlags
+	fs := newFlagSet(name)
+	machine.register(fs)
+	frames := fs.Int("frames", 60, "number of frames to run, the run timing out after them when a stop condition is set")
+	stopPC := fs.String("stop-pc", "", "stop when the program counter reaches this address")
+	stopMemory := fs.String("stop-memory", "", "stop when memory holds a value, given as address=value (0xA000=0x00)")
+	stopSerial := fs.String("stop-serial", "", "stop when this text is sent on the serial port")
+	stopLoop := fs.Bool("stop-loop", false, "stop when the program enters an infinite loop")
+	inputScript := fs.String("input", "", "input script, one \"<frame> <buttons>\" line per change (60 Start, 90 A+Right, 120 -)")
+	screenshot := fs.String("screenshot", "", "PNG file the LCD is saved to at the end of the run")
+	screenshotAt := fs.String("screenshot-at", "", "comma separated frames the LCD is saved at, as <screenshot>-<frame>.png")
+	printSerial := fs.Bool("serial", false, "print the serial output once done")
+	speed := fs.String("speed", "unlimited", "emulation speed, from 0.25x to 16x, or unlimited")
+	printFPS := fs.Bool("fps", false, "print the emulated frames per second once done")
+	record := fs.String("record", "", "record the input of the run from power on into this movie file, or save the imported movie played back with its checksums")
+	play := fs.String("play", "", "play this movie file (.gbm, BizHawk .bk2 or VisualBoyAdvance .vbm) back, for its whole length unless --frames is given")
+	verify := fs.Bool("verify", false, "fail when the movie played back desyncs from its recording")
+	var breakpoints []emulator.Breakpoint
+	fs.Func("break", "stop on this breakpoint: [bank:]address, watchpoint r|w|x start[-end][=value] or op opcode, may be repeated", func(text string) error {
+		b, err := emulator.ParseBreakpoint(text)
+		breakpoints = append(breakpoints, b)
+		return err
+	})
+	var cheats []emulator.Cheat
+	fs.Func("cheat", "enable this Game Genie (ABC-DEF-GHI) or GameShark (01VVLLHH) code, may be repeated", func(code string) error {
+		c, err := emulator.ParseCheat(code)
+		c.Enabled = true
+		cheats = append(cheats, c)
+		return err
+	})
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	framesSet := false
+	fs.Visit(func(f *flag.Flag) {
+		framesSet = framesSet || f.Name == "frames"
+	})
+	if *verify && *play == "" {
+		fmt.Fprintln(os.Stderr, "--verify needs a movie to --play")
+		return exitUsage
+	}
+	if *record != "" && strings.EqualFold(filepath.Ext(*play), movieExtension) {
+		// Only imported movies are saved once played back, native ones already hold their checksums
+		fmt.Fprintf(os.Stderr, "--record can't be used while playing a %s movie back\n", movieExtension)
+		return exitUsage
+	}
+
+	options := headless.Options{Frames: *frames, Screenshots: map[uint64]string{}}
+	var err error
+	if options.Speed, err = emulator.ParseSpeed(*speed); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if *stopPC != "" {
+		pc, err := strconv.ParseUint(*stopPC, 0, 16)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid address %q\n", *stopPC)
+			return exitUsage
+		}
+		options.Stop = append(options.Stop, headless.PCReached(uint16(pc)))
+	}
+	if *stopMemory != "" {
+		address, value, found := strings.Cut(*stopMemory, "=")
+		a, errA := strconv.ParseUint(address, 0, 16)
+		v, errV := strconv.ParseUint(value, 0, 8)
+		if !found || errA != nil || errV != nil {
+			fmt.Fprintf(os.Stderr, "invalid memory condition %q, expected address=value\n", *stopMemory)
+			return exitUsage
+		}
+		options.Stop = append(options.Stop, headless.MemoryEquals(uint16(a), uint8(v)))
+	}
+	if *stopSerial != "" {
+		options.Stop = append(options.Stop, headless.SerialContains(*stopSerial))
+	}
+	if *stopLoop {
+		options.Stop = append(options.Stop, headless.InfiniteLoop(infiniteLoopRepeats))
+	}
+	if *inputScript != "" {
+		f, err := os.Open(*inputScript)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		options.Input, err = headless.ParseInputScript(f)
+		_ = f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *inputScript, err)
+			return exitUsage
+		}
+	}
+	if *screenshotAt != "" {
+		base := *screenshot
+		if base == "" {
+			base = "screenshot.png"
+		}
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+		for _, f := range strings.Split(*screenshotAt, ",") {
+			frame, err := strconv.ParseUint(strings.TrimSpace(f), 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid frame %q\n", f)
+				return exitUsage
+			}
+			options.Screenshots[frame] = fmt.Sprintf("%s-%d.png", base, frame)
+		}
+	}
+
+	dmg, code := machine.newDMG(positional[0])
+	if dmg == nil {
+		return code
+	}
+	dmg.SetCheats(cheats)
+	for _, b := range breakpoints {
+		dmg.AddBreakpoint(b)
+	}
+	var movie *emulator.Movie
+	if *play != "" {
+		var err error
+		movie, err = readMovieFile(*play, dmg.ROM)
+		var pathErr *os.PathError
+		switch {
+		case errors.As(err, &pathErr):
+			fmt.Fprintf(os.Stderr, "error reading movie: %v\n", err)
+			return exitError
+		case err == nil:
+			err = dmg.PlayMovie(movie, *verify)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *play, err)
+			return exitFailure
+		}
+		if !framesSet {
+			options.Frames = len(movie.Frames)
+		}
+	} else if *record != "" {
+		if err := dmg.RecordMovie(false); err != nil {
+			fmt.Fprintf(os.Stderr, "error recording movie: %v\n", err)
+			return exitFailure
+		}
+	}
+	result, err := headless.Run(dmg, options)
+	if recorded := dmg.StopMovie(); recorded != nil {
+		movie = recorded
+	}
+	if *record != "" && movie != nil {
+		// Imported movies are saved once played back, with the checksums computed along the way
+		if err := writeMovieFile(movie, *record); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing movie: %v\n", err)
+			return exitError
+		}
+	}
+	if *printSerial {
+		fmt.Println(string(dmg.SerialOutput()))
+	}
+	if *printFPS {
+		fmt.Printf("%d frames in %v (%.1f fps)\n", result.Frames, result.Duration.Round(time.Millisecond), result.FPS())
+	}
+	// The screenshot is written whatever the outcome, showing where a timed out or crashed ROM stopped
+	var screenshotErr error
+	if *screenshot != "" {
+		if screenshotErr = headless.WritePNG(dmg, *screenshot); screenshotErr != nil {
+			fmt.Fprintf(os.Stderr, "error writing screenshot: %v\n", screenshotErr)
+		}
+	}
+	var pathErr *os.PathError
+	switch {
+	case errors.As(err, &pathErr):
+		fmt.Fprintf(os.Stderr, "error writing screenshot: %v\n", err)
+		return exitError
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	case result.Stopped != "":
+		fmt.Printf("stopped at frame %d: %s\n", result.Frames, result.Stopped)
+	}
+	if screenshotErr != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+func disasmCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	start := fs.String("start", "0x100", "address to start from")
+	lines := fs.Int("lines", 50, "number of instructions to disassemble, -1 for the whole address space")
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	startPos, err := strconv.ParseUint(*start, 0, 16)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid start address %q\n", *start)
+		return exitUsage
+	}
+
+	dmg := emulator.MakeDMG()
+	if err := dmg.LoadROM(positional[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "error loading ROM %s: %v\n", positional[0], err)
+		return exitError
+	}
+	fmt.Print(dmg.Disassemble(uint16(startPos), *lines))
+	return exitOK
+}
+
+func infoCommand(name string, args []string) int {
+	fs := newFlagSet(name)
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	rom, err := emulator.ReadROM(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading ROM %s: %v\n", positional[0], err)
+		return exitError
+	}
+	h, err := emulator.ParseHeader(rom.Data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", positional[0], err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "File:\t%s\n", rom.Name)
+	fmt.Fprintf(w, "Title:\t%s\n", h.Title)
+	if h.Manufacturer != "" {
+		fmt.Fprintf(w, "Manufacturer:\t%s\n", h.Manufacturer)
+	}
+	fmt.Fprintf(w, "CGB flag:\t0x%02X\n", h.CGBFlag)
+	fmt.Fprintf(w, "SGB flag:\t0x%02X\n", h.SGBFlag)
+	fmt.Fprintf(w, "Cartridge type:\t%s\n", h.CartridgeTypeName())
+	fmt.Fprintf(w, "ROM size:\t%d KB\n", h.ROMSize/1024)
+	fmt.Fprintf(w, "RAM size:\t%d KB\n", h.RAMSize/1024)
+	destination := "Overseas"
+	if h.Japanese {
+		destination = "Japan"
+	}
+	fmt.Fprintf(w, "Destination:\t%s\n", destination)
+	if h.NewLicensee != "" {
+		fmt.Fprintf(w, "Licensee:\t%s\n", h.NewLicensee)
+	} else {
+		fmt.Fprintf(w, "Licensee:\t0x%02X\n", h.OldLicensee)
+	}
+	fmt.Fprintf(w, "Version:\t%d\n", h.Version)
+	fmt.Fprintf(w, "Header checksum:\t0x%02X (%s)\n", h.HeaderChecksum, checksumStatus(emulator.HeaderChecksumOK(rom.Data)))
+	fmt.Fprintf(w, "Global checksum:\t0x%04X (%s)\n", h.GlobalChecksum, checksumStatus(emulator.GlobalChecksumOK(rom.Data)))
+	fmt.Fprintf(w, "CRC32:\t%08X\n", crc32.ChecksumIEEE(rom.Data))
+	_ = w.Flush()
+	return exitOK
+}
+
+func checksumStatus(ok bool) string {
+	if ok {
+		return "OK"
+	}
+	return "BAD"
+}
+
+func testCommand(name string, args []string) int {
+	var machine machineFlags
+	fs := newFlagSet(name)
+	machine.register(fs)
+	frames := fs.Int("frames", 3600, "frames each ROM can run for before timing out")
+	junit := fs.String("junit", "", "JUnit XML report to write")
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	dir := positional[0]
+
+	roms, err := findROMs(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	var results []headless.TestResult
+	for _, path := range roms {
+		rel, _ := filepath.Rel(dir, path)
+		dmg, code := machine.newDMG(path)
+		if dmg == nil {
+			if code == exitUsage {
+				return code
+			}
+			results = append(results, headless.TestResult{ROM: rel, Status: headless.TestCrashed, Message: "could not be loaded"})
+			continue
+		}
+		results = append(results, headless.RunTestROM(dmg, rel, *frames))
+	}
+
+	if err := headless.WriteSummary(os.Stdout, results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if *junit != "" {
+		if err := writeJUnitFile(*junit, filepath.Base(filepath.Clean(dir)), results); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing JUnit report: %v\n", err)
+			return exitError
+		}
+	}
+
+	for _, r := range results {
+		if r.Status != headless.TestPassed {
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+// writeJUnitFile Write results as a JUnit XML report at path
+func writeJUnitFile(path string, suite string, results []headless.TestResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := headless.WriteJUnit(f, suite, results); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// findROMs List the ROMs under dir, sorted by path
+func findROMs(dir string) ([]string, error) {
+	var roms []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isROMFile(path) {
+			roms = append(roms, path)
+		}
+		return nil
+	})
+	sort.Strings(roms)
+	return roms, err
+}
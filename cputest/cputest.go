@@ -0,0 +1,275 @@
+// Package cputest Run the SM83 single step tests (https://github.com/SingleStepTests/sm83) against the CPU.
+// Each JSON file holds the cases of an opcode : an initial state, a single instruction executed on a flat RAM bus,
+// and the expected final state & bus cycles. Test files aren't shipped with the repository.
+package cputest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// State CPU & memory state of a test case
+type State struct {
+	PC  uint16     `json:"pc"`
+	SP  uint16     `json:"sp"`
+	A   uint8      `json:"a"`
+	B   uint8      `json:"b"`
+	C   uint8      `json:"c"`
+	D   uint8      `json:"d"`
+	E   uint8      `json:"e"`
+	F   uint8      `json:"f"`
+	H   uint8      `json:"h"`
+	L   uint8      `json:"l"`
+	IME uint8      `json:"ime"`
+	IE  *uint8     `json:"ie,omitempty"` // Missing from some test versions
+	RAM [][2]int64 `json:"ram"`          // Address, value pairs
+}
+
+// Cycle Bus activity during one M-cycle : address, value & "r-m", "-wm" or "---" when idle
+type Cycle [3]any
+
+// Case Single test case
+type Case struct {
+	Name    string  `json:"name"`
+	Initial State   `json:"initial"`
+	Final   State   `json:"final"`
+	Cycles  []Cycle `json:"cycles"`
+}
+
+// Options Harness configuration
+type Options struct {
+	CheckBus bool // Also compare the memory accesses made by the CPU with the bus cycles of the cases
+}
+
+// Divergence Value differing from the expected final state
+type Divergence struct {
+	Field    string // Register, flag, "ram[addr]", "bus" or "panic"
+	Expected string
+	Got      string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.Field, d.Expected, d.Got)
+}
+
+// OpcodeReport Results of the cases of an opcode file
+type OpcodeReport struct {
+	Opcode     string         // Test file name, without extension ("00", "cb 11")
+	Cases      int            // Cases run
+	Failed     int            // Cases with at least one divergence
+	Fields     map[string]int // Failed cases by diverging field
+	FirstCase  string         // Name of the first failed case
+	FirstDiffs []Divergence   // Divergences of the first failed case
+}
+
+// LoadCases Read the cases of a JSON test file
+func LoadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cases, nil
+}
+
+// RunCase Execute the instruction of a case, returning how the final state diverges from the expected one
+func RunCase(c Case, options Options) (divergences []Divergence) {
+	dmg := emulator.MakeDMGWithOptions(emulator.Options{Model: emulator.ModelDMG, FlatMemory: true})
+	cpu := dmg.Gbz80
+	s := c.Initial
+	cpu.Af = uint16(s.A)<<8 | uint16(s.F)
+	cpu.Bc = uint16(s.B)<<8 | uint16(s.C)
+	cpu.De = uint16(s.D)<<8 | uint16(s.E)
+	cpu.Hl = uint16(s.H)<<8 | uint16(s.L)
+	cpu.Sp = s.SP
+	cpu.Pc = s.PC
+	cpu.Ime = s.IME != 0
+	if s.IE != nil {
+		dmg.Memory[emulator.InterruptEnableReg] = *s.IE
+	}
+	for _, entry := range s.RAM {
+		dmg.Memory[uint16(entry[0])] = uint8(entry[1])
+	}
+
+	var bus []emulator.BusAccess
+	if options.CheckBus {
+		dmg.OnBusAccess = func(access emulator.BusAccess) {
+			bus = append(bus, access)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			divergences = append(divergences, Divergence{Field: "panic", Expected: "no panic", Got: fmt.Sprint(r)})
+		}
+	}()
+	dmg.ExecuteCurrentInstruction()
+	dmg.OnBusAccess = nil
+
+	f := c.Final
+	check := func(field string, expected, got uint64, width int) {
+		if expected != got {
+			divergences = append(divergences, Divergence{
+				Field:    field,
+				Expected: fmt.Sprintf("0x%0*X", width, expected),
+				Got:      fmt.Sprintf("0x%0*X", width, got),
+			})
+		}
+	}
+	check("A", uint64(f.A), uint64(cpu.A()), 2)
+	check("B", uint64(f.B), uint64(cpu.B()), 2)
+	check("C", uint64(f.C), uint64(cpu.C()), 2)
+	check("D", uint64(f.D), uint64(cpu.D()), 2)
+	check("E", uint64(f.E), uint64(cpu.E()), 2)
+	check("H", uint64(f.H), uint64(cpu.H()), 2)
+	check("L", uint64(f.L), uint64(cpu.L()), 2)
+	check("SP", uint64(f.SP), uint64(cpu.Sp), 4)
+	check("PC", uint64(f.PC), uint64(cpu.Pc), 4)
+	for _, flag := range []struct {
+		name string
+		mask uint8
+	}{{"flag Z", 0x80}, {"flag N", 0x40}, {"flag H", 0x20}, {"flag C", 0x10}} {
+		check(flag.name, uint64(f.F&flag.mask/flag.mask), uint64(cpu.F()&flag.mask/flag.mask), 1)
+	}
+	check("flags 0-3", uint64(f.F&0x0F), uint64(cpu.F()&0x0F), 1)
+	ime := uint64(0)
+	if cpu.Ime {
+		ime = 1
+	}
+	check("IME", uint64(f.IME), ime, 1)
+	if f.IE != nil {
+		check("IE", uint64(*f.IE), uint64(dmg.Memory[emulator.InterruptEnableReg]), 2)
+	}
+	for _, entry := range f.RAM {
+		address := uint16(entry[0])
+		check(fmt.Sprintf("ram[0x%04X]", address), uint64(entry[1]), uint64(dmg.Memory[address]), 2)
+	}
+
+	if options.CheckBus {
+		if expected, got := formatCycles(c.Cycles), formatBus(bus); expected != got {
+			divergences = append(divergences, Divergence{Field: "bus", Expected: expected, Got: got})
+		}
+	}
+	return divergences
+}
+
+// formatCycles Memory accesses of bus cycles, idle cycles being skipped
+func formatCycles(cycles []Cycle) string {
+	var accesses []string
+	for _, cycle := range cycles {
+		address, okA := cycle[0].(float64)
+		value, okV := cycle[1].(float64)
+		kind, _ := cycle[2].(string)
+		if !okA || !okV || len(kind) < 2 {
+			continue
+		}
+		switch {
+		case kind[0] == 'r':
+			accesses = append(accesses, fmt.Sprintf("r %04X=%02X", int(address), int(value)))
+		case kind[1] == 'w':
+			accesses = append(accesses, fmt.Sprintf("w %04X=%02X", int(address), int(value)))
+		}
+	}
+	return strings.Join(accesses, " ")
+}
+
+// formatBus Memory accesses logged during the instruction
+func formatBus(bus []emulator.BusAccess) string {
+	var accesses []string
+	for _, access := range bus {
+		kind := "r"
+		if access.Write {
+			kind = "w"
+		}
+		accesses = append(accesses, fmt.Sprintf("%s %04X=%02X", kind, access.Address, access.Value))
+	}
+	return strings.Join(accesses, " ")
+}
+
+// RunFile Run the cases of a JSON test file
+func RunFile(path string, options Options) (OpcodeReport, error) {
+	cases, err := LoadCases(path)
+	if err != nil {
+		return OpcodeReport{}, err
+	}
+	report := OpcodeReport{
+		Opcode: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Fields: map[string]int{},
+	}
+	for _, c := range cases {
+		report.Cases++
+		divergences := RunCase(c, options)
+		if len(divergences) == 0 {
+			continue
+		}
+		report.Failed++
+		for _, d := range divergences {
+			report.Fields[d.Field]++
+		}
+		if report.FirstDiffs == nil {
+			report.FirstCase = c.Name
+			report.FirstDiffs = divergences
+		}
+	}
+	return report, nil
+}
+
+// RunDir Run every JSON test file of dir, sorted by opcode
+func RunDir(dir string, options Options) ([]OpcodeReport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no JSON test file found in %s", dir)
+	}
+	sort.Strings(files)
+	var reports []OpcodeReport
+	for _, file := range files {
+		report, err := RunFile(file, options)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// WriteReport Print the diverging opcodes, with the fields they diverge on & their first failed case
+func WriteReport(w io.Writer, reports []OpcodeReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPCODE\tFAILED\tFIELDS\tFIRST FAILURE")
+	passed := 0
+	for _, r := range reports {
+		if r.Failed == 0 {
+			passed++
+			continue
+		}
+		fields := make([]string, 0, len(r.Fields))
+		for field := range r.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		first := make([]string, len(r.FirstDiffs))
+		for i, d := range r.FirstDiffs {
+			first[i] = d.String()
+		}
+		fmt.Fprintf(tw, "%s\t%d/%d\t%s\t%s: %s\n", r.Opcode, r.Failed, r.Cases, strings.Join(fields, ","), r.FirstCase, strings.Join(first, "; "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d/%d opcodes passed\n", passed, len(reports))
+	return err
+}
@@ -0,0 +1,106 @@
+package cputest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Cases in the SM83 test format : NOP & LD B,C
+const testCases = `[
+	{
+		"name": "00 0000",
+		"initial": {"pc": 256, "sp": 65534, "a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 176, "h": 6, "l": 7, "ime": 0, "ie": 0, "ram": [[256, 0]]},
+		"final": {"pc": 257, "sp": 65534, "a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 176, "h": 6, "l": 7, "ime": 0, "ie": 0, "ram": [[256, 0]]},
+		"cycles": [[256, 0, "r-m"]]
+	},
+	{
+		"name": "41 0000",
+		"initial": {"pc": 49152, "sp": 65534, "a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 0, "h": 6, "l": 7, "ime": 0, "ram": [[49152, 65]]},
+		"final": {"pc": 49153, "sp": 65534, "a": 1, "b": 3, "c": 3, "d": 4, "e": 5, "f": 0, "h": 6, "l": 7, "ime": 0, "ram": [[49152, 65]]},
+		"cycles": [[49152, 65, "r-m"]]
+	}
+]`
+
+func TestRunCase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cases.json")
+	if err := os.WriteFile(path, []byte(testCases), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadCases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		if divergences := RunCase(c, Options{CheckBus: true}); len(divergences) != 0 {
+			t.Errorf("%s: unexpected divergences %v", c.Name, divergences)
+		}
+	}
+
+	// Wrong expectations are reported on the right fields
+	c := cases[1]
+	c.Final.B = 0x42
+	c.Final.F = 0x80
+	c.Final.RAM = [][2]int64{{49152, 0}}
+	fields := map[string]bool{}
+	for _, d := range RunCase(c, Options{}) {
+		fields[d.Field] = true
+	}
+	for _, field := range []string{"B", "flag Z", "ram[0xC000]"} {
+		if !fields[field] {
+			t.Errorf("expected a divergence on %s, got %v", field, fields)
+		}
+	}
+	if len(fields) != 3 {
+		t.Errorf("expected 3 divergences, got %v", fields)
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	dir := t.TempDir()
+	failing := strings.Replace(testCases, `"b": 3`, `"b": 9`, 1)
+	if err := os.WriteFile(filepath.Join(dir, "00.json"), []byte(testCases), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "41.json"), []byte(failing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := RunDir(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].Failed != 0 || reports[1].Failed != 1 || reports[1].Fields["B"] != 1 {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+
+	var out bytes.Buffer
+	if err := WriteReport(&out, reports); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1/2 opcodes passed") || !strings.Contains(out.String(), "41 0000: B: expected 0x09, got 0x03") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+// TestSM83 Run the whole suite when SM83_TESTS points to the directory of the JSON test files
+func TestSM83(t *testing.T) {
+	dir := os.Getenv("SM83_TESTS")
+	if dir == "" {
+		t.Skip("SM83_TESTS not set")
+	}
+	reports, err := RunDir(dir, Options{CheckBus: os.Getenv("SM83_CHECK_BUS") != ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	_ = WriteReport(&out, reports)
+	t.Log("\n" + out.String())
+	for _, r := range reports {
+		if r.Failed != 0 {
+			t.Errorf("opcode %s: %d/%d cases diverge", r.Opcode, r.Failed, r.Cases)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+const (
+	scalePreference      = "display.scale"
+	debugPanelPreference = "display.debugPanels"
+	defaultScale         = 3
+	maxScale             = 8
+	scaleFit             = 0 // Fill the window, keeping the LCD aspect ratio
+)
+
+// display LCD area of the window : integer scaling or fit to window, fullscreen & debug panels visibility.
+// The LCD is shown inside the debug layout, or alone for a play-only window.
+type display struct {
+	window      fyne.Window
+	prefs       fyne.Preferences
+	screen      *canvas.Image
+	area        *fyne.Container // Holds the screen, centered at its scale or filling the area
+	lcdPanel    *fyne.Container // Place of the area in the debug layout
+	debugLayout fyne.CanvasObject
+	scale       int
+	factor      int // Filtered frames are factor times larger than the LCD
+	showDebug   bool
+	menuItems   []*fyne.MenuItem // Scale items, checked according to the current scale
+	debugItem   *fyne.MenuItem
+	fullItem    *fyne.MenuItem
+	onMenu      func() // Called when menu items need a refresh
+}
+
+// newDisplay Create the LCD area, restoring the scale & panels visibility from preferences
+func newDisplay(w fyne.Window, prefs fyne.Preferences, screen *canvas.Image) *display {
+	screen.ScaleMode = canvas.ImageScalePixels
+	screen.FillMode = canvas.ImageFillContain
+	d := &display{
+		window:    w,
+		prefs:     prefs,
+		screen:    screen,
+		area:      container.NewStack(),
+		scale:     prefs.IntWithFallback(scalePreference, defaultScale),
+		factor:    1,
+		showDebug: prefs.BoolWithFallback(debugPanelPreference, true),
+	}
+	if d.scale < scaleFit || d.scale > maxScale {
+		d.scale = defaultScale
+	}
+	return d
+}
+
+// setLayout Give the debug layout, the LCD area being shown in lcdPanel, then show the window content
+func (d *display) setLayout(debugLayout fyne.CanvasObject, lcdPanel *fyne.Container) {
+	d.debugLayout = debugLayout
+	d.lcdPanel = lcdPanel
+	d.applyScale()
+	d.applyLayout()
+}
+
+// SetScale Use an integer scale (1 to 8), or scaleFit to fill the window
+func (d *display) SetScale(scale int) {
+	d.scale = scale
+	d.prefs.SetInt(scalePreference, scale)
+	d.applyScale()
+	d.fitWindow()
+	d.refreshMenu()
+}
+
+// SetFilterFactor Give how many times larger than the LCD filtered frames are, integer scales being rounded to
+// a multiple of it so that every filtered pixel is shown with the same number of screen pixels
+func (d *display) SetFilterFactor(factor int) {
+	d.factor = max(factor, 1)
+	if d.lcdPanel != nil {
+		d.applyScale()
+		d.fitWindow()
+	}
+}
+
+// fitWindow Shrink or grow the window around the LCD, when it is shown alone at an integer scale
+func (d *display) fitWindow() {
+	if d.scale != scaleFit && !d.showDebug && !d.window.FullScreen() {
+		d.window.Resize(d.window.Content().MinSize())
+	}
+}
+
+// lcdScale Get the integer scale the LCD is shown at, the multiple of the filter factor closest to the chosen scale
+func (d *display) lcdScale() int {
+	return max((d.scale+d.factor/2)/d.factor, 1) * d.factor
+}
+
+// SetDebugPanels Show or hide the registers & memory panels
+func (d *display) SetDebugPanels(show bool) {
+	d.showDebug = show
+	d.prefs.SetBool(debugPanelPreference, show)
+	d.applyLayout()
+	d.refreshMenu()
+}
+
+// ToggleFullScreen Enter or leave fullscreen
+func (d *display) ToggleFullScreen() {
+	d.window.SetFullScreen(!d.window.FullScreen())
+	d.refreshMenu()
+}
+
+func (d *display) applyScale() {
+	if d.scale == scaleFit {
+		// Image fill mode keeps the aspect ratio, with borders on the sides
+		d.screen.SetMinSize(fyne.NewSize(emulator.ScreenWidth, emulator.ScreenHeight))
+		d.area.Objects = []fyne.CanvasObject{d.screen}
+	} else {
+		scale := d.lcdScale()
+		d.screen.SetMinSize(fyne.NewSize(float32(emulator.ScreenWidth*scale), float32(emulator.ScreenHeight*scale)))
+		d.area.Objects = []fyne.CanvasObject{container.NewCenter(d.screen)}
+	}
+	d.area.Refresh()
+}
+
+func (d *display) applyLayout() {
+	d.lcdPanel.Remove(d.area)
+	if d.showDebug {
+		d.lcdPanel.Add(d.area)
+		d.window.SetContent(d.debugLayout)
+	} else {
+		d.window.SetContent(d.area)
+	}
+}
+
+// menu Build the "View" menu
+func (d *display) menu() *fyne.Menu {
+	d.menuItems = nil
+	for scale := 1; scale <= maxScale; scale++ {
+		d.menuItems = append(d.menuItems, fyne.NewMenuItem(fmt.Sprintf("%dx", scale), func() {
+			d.SetScale(scale)
+		}))
+	}
+	d.menuItems = append(d.menuItems, fyne.NewMenuItem("Fit to Window", func() {
+		d.SetScale(scaleFit)
+	}))
+	d.fullItem = fyne.NewMenuItem("Fullscreen (F11)", d.ToggleFullScreen)
+	d.debugItem = fyne.NewMenuItem("Debug Panels", func() {
+		d.SetDebugPanels(!d.showDebug)
+	})
+
+	items := append([]*fyne.MenuItem{}, d.menuItems...)
+	items = append(items, fyne.NewMenuItemSeparator(), d.fullItem, d.debugItem)
+	d.refreshMenu()
+	return fyne.NewMenu("View", items...)
+}
+
+// refreshMenu Check the items matching the current state
+func (d *display) refreshMenu() {
+	for i, item := range d.menuItems {
+		scale := i + 1
+		if i == maxScale {
+			scale = scaleFit
+		}
+		item.Checked = scale == d.scale
+	}
+	if d.fullItem != nil {
+		d.fullItem.Checked = d.window.FullScreen()
+		d.debugItem.Checked = d.showDebug
+	}
+	if d.onMenu != nil {
+		d.onMenu()
+	}
+}
@@ -0,0 +1,133 @@
+package emulator
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ROMs can be loaded as is, or from a .zip or .gz archive.
+// The ROM keeps the name of the archived file, so that save files are named after it.
+
+// ROMExtensions Extensions of the ROM files, inside archives or not
+var ROMExtensions = []string{".gb", ".gbc", ".sgb"}
+
+// ArchiveExtensions Extensions of the archives ROMs can be loaded from
+var ArchiveExtensions = []string{".zip", ".gz"}
+
+// ErrNoROMInArchive Returned when a zip archive doesn't hold any ROM
+var ErrNoROMInArchive = errors.New("no ROM found in archive")
+
+// ROMFile ROM read from disk
+type ROMFile struct {
+	Path  string // File the ROM was read from, possibly an archive
+	Name  string // Name of the ROM file, the archived one for archives
+	Data  []uint8
+	Patch string // Patch file applied to Data, if any
+}
+
+// isROMName Does name have a ROM extension
+func isROMName(name string) bool {
+	return slices.Contains(ROMExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// isMacOSMetadata Is name a resource fork macOS adds to the archives it creates
+func isMacOSMetadata(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(name[strings.LastIndex(name, "/")+1:], "._")
+}
+
+// ReadROM Read the ROM at path, for a zip archive this is its first ROM entry
+func ReadROM(path string) (ROMFile, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+		entries, err := ZipROMEntries(path)
+		if err != nil {
+			return ROMFile{}, err
+		}
+		return ReadZipROM(path, entries[0])
+	case ".gz":
+		return readGzipROM(path)
+	}
+
+	data, err := readLimited(os.Open(path))
+	if err != nil {
+		return ROMFile{}, err
+	}
+	return ROMFile{Path: path, Name: filepath.Base(path), Data: data}, nil
+}
+
+// ZipROMEntries List the ROMs held by a zip archive, in archive order
+func ZipROMEntries(path string) ([]string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []string
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() && isROMName(f.Name) && !isMacOSMetadata(f.Name) {
+			entries = append(entries, f.Name)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoROMInArchive)
+	}
+	return entries, nil
+}
+
+// ReadZipROM Read the entry ROM from the zip archive at path
+func ReadZipROM(path string, entry string) (ROMFile, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return ROMFile{}, err
+	}
+	defer r.Close()
+
+	data, err := readLimited(r.Open(entry))
+	if err != nil {
+		return ROMFile{}, err
+	}
+	return ROMFile{Path: path, Name: filepath.Base(entry), Data: data}, nil
+}
+
+// readGzipROM Read a gzipped ROM, named after the gzip header or the file name without .gz
+func readGzipROM(path string) (ROMFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ROMFile{}, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return ROMFile{}, err
+	}
+	data, err := readLimited(gz, nil)
+	if err != nil {
+		return ROMFile{}, err
+	}
+
+	name := filepath.Base(gz.Name)
+	if gz.Name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return ROMFile{Path: path, Name: name, Data: data}, nil
+}
+
+// readLimited Read a whole ROM, stopping just past the addressable memory so that a huge file can't exhaust memory
+func readLimited(r io.Reader, err error) ([]uint8, error) {
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	return io.ReadAll(io.LimitReader(r, MemorySize+1))
+}
@@ -0,0 +1,115 @@
+package emulator
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestZip Create a zip archive holding files, in the given order
+func writeTestZip(t *testing.T, path string, names []string, contents [][]byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for i, name := range names {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write(contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadROMFromZip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "collection.zip")
+	first := bytes.Repeat([]byte{0x11}, 0x8000)
+	second := bytes.Repeat([]byte{0x22}, 0x8000)
+	writeTestZip(t, path,
+		[]string{"readme.txt", "__MACOSX/games/._tetris.gb", "games/._tetris.gb", "games/tetris.gb", "zelda.gbc"},
+		[][]byte{[]byte("hello"), []byte("fork"), []byte("fork"), first, second},
+	)
+
+	entries, err := ZipROMEntries(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != "games/tetris.gb" || entries[1] != "zelda.gbc" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	dmg := MakeDMG()
+	if err := dmg.LoadROM(path); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dmg.ROM, first) {
+		t.Error("first ROM entry should be loaded")
+	}
+	if want := filepath.Join(dir, "tetris.state"); dmg.SavePath(".state") != want {
+		t.Errorf("save path %s, want %s", dmg.SavePath(".state"), want)
+	}
+
+	rom, err := ReadZipROM(path, "zelda.gbc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rom.Name != "zelda.gbc" || !bytes.Equal(rom.Data, second) {
+		t.Error("second ROM entry not read")
+	}
+}
+
+func TestLoadROMFromZipWithoutROM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.zip")
+	writeTestZip(t, path, []string{"readme.txt"}, [][]byte{[]byte("hello")})
+
+	if err := MakeDMG().LoadROM(path); !errors.Is(err, ErrNoROMInArchive) {
+		t.Errorf("expected ErrNoROMInArchive, got %v", err)
+	}
+}
+
+func TestLoadROMFromGzip(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte{0x33}, 0x8000)
+	for _, tc := range []struct {
+		file   string
+		header string
+		name   string
+	}{
+		{"tetris.gb.gz", "", "tetris.gb"},
+		{"archive.gz", "pokemon.gb", "pokemon.gb"},
+	} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Name = tc.header
+		_, _ = gz.Write(data)
+		_ = gz.Close()
+		path := filepath.Join(dir, tc.file)
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		dmg := MakeDMG()
+		if err := dmg.LoadROM(path); err != nil {
+			t.Fatal(err)
+		}
+		if dmg.ROMName != tc.name {
+			t.Errorf("%s: ROM name %s, want %s", tc.file, dmg.ROMName, tc.name)
+		}
+		if !bytes.Equal(dmg.ROM, data) {
+			t.Errorf("%s: ROM data differs", tc.file)
+		}
+	}
+}
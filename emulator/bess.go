@@ -0,0 +1,270 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// BESS (Best Effort Save State) support, to exchange states with other emulators (SameBoy, Emulicious...)
+// See : https://github.com/LIJI32/SameBoy/blob/master/BESS.md
+//
+// BESS blocks are appended after the native state, with a footer at the very end of the file :
+//
+//	+--------+-------------------------------------+
+//	| Size   | Content                             |
+//	+--------+-------------------------------------+
+//	| 4      | Offset of the first BESS block      |
+//	| 4      | Magic "BESS"                        |
+//	+--------+-------------------------------------+
+//
+// BESS blocks share the layout of native chunks (ID, length & payload).
+// Memory areas are not duplicated, the CORE block points at the memory stored in the native bus chunk.
+
+const (
+	BESSMagic        = "BESS"
+	BESSMajorVersion = 1
+	BESSMinorVersion = 1
+	BESSEmulatorName = "gogbemulator"
+)
+
+// BESS blocks IDs
+const (
+	bessBlockName = "NAME"
+	bessBlockInfo = "INFO"
+	bessBlockCore = "CORE"
+	bessBlockMBC  = "MBC "
+	bessBlockRTC  = "RTC "
+	bessBlockEnd  = "END "
+)
+
+// BESS CORE block execution states
+const (
+	bessRunning = 0
+	bessHalted  = 1
+	bessStopped = 2
+)
+
+// bessModels BESS model identifiers : family, model & revision, unspecified chars being spaces
+var bessModels = map[Model]string{
+	ModelDMG:  "GDB ",
+	ModelDMG0: "GD0 ",
+	ModelMGB:  "GM  ",
+	ModelSGB:  "SN  ",
+	ModelSGB2: "S2  ",
+	ModelCGB:  "CCE ",
+	ModelAGB:  "CAA ",
+}
+
+// bessBuffer Size & offset in the file of a memory area
+type bessBuffer struct {
+	Size   uint32
+	Offset uint32
+}
+
+type bessCore struct {
+	MajorVersion   uint16
+	MinorVersion   uint16
+	Model          [4]byte
+	Pc, Af, Bc, De uint16
+	Hl, Sp         uint16
+	Ime            uint8
+	Ie             uint8
+	ExecutionState uint8
+	Reserved       uint8
+	IO             [0x80]uint8
+	RAM            bessBuffer
+	VRAM           bessBuffer
+	MBCRAM         bessBuffer
+	OAM            bessBuffer
+	HRAM           bessBuffer
+	BGPalettes     bessBuffer
+	OBJPalettes    bessBuffer
+}
+
+type bessInfo struct {
+	Title          [16]byte
+	GlobalChecksum [2]byte
+}
+
+// writeBESS Append the BESS blocks & footer to state, memory being the offset of the 64KB memory in state
+func (dmg *DMG) writeBESS(state *bytes.Buffer, memory uint32) error {
+	first := uint32(state.Len())
+
+	if err := writeStateChunk(state, bessBlockName, []byte(BESSEmulatorName)); err != nil {
+		return err
+	}
+
+	var info bessInfo
+	copy(info.Title[:], dmg.Memory[CartridgeHeaderTitle:])
+	copy(info.GlobalChecksum[:], dmg.Memory[CartridgeHeaderGlobalChecksum:])
+	if err := writeStateChunk(state, bessBlockInfo, info); err != nil {
+		return err
+	}
+
+	core := bessCore{
+		MajorVersion: BESSMajorVersion,
+		MinorVersion: BESSMinorVersion,
+		Pc:           dmg.Gbz80.Pc,
+		Af:           dmg.Gbz80.Af,
+		Bc:           dmg.Gbz80.Bc,
+		De:           dmg.Gbz80.De,
+		Hl:           dmg.Gbz80.Hl,
+		Sp:           dmg.Gbz80.Sp,
+		Ime:          boolToU8(dmg.Gbz80.Ime),
+		Ie:           dmg.Memory[InterruptEnableReg],
+		RAM:          bessBuffer{WRAMEnd - WRAMStart + 1, memory + WRAMStart},
+		VRAM:         bessBuffer{VRAMEnd - VRAMStart + 1, memory + VRAMStart},
+		MBCRAM:       bessBuffer{dmg.cartridgeRAMSize(), memory + ExternalRAMStart},
+		OAM:          bessBuffer{OAMEnd - OAMStart + 1, memory + OAMStart},
+		HRAM:         bessBuffer{HRAMEnd - HRAMStart, memory + HRAMStart},
+	}
+	copy(core.Model[:], bessModels[dmg.Model])
+	if dmg.Gbz80.Halted {
+		core.ExecutionState = bessHalted
+	}
+	copy(core.IO[:], dmg.Memory[IOPortStart:IOPortEnd+1])
+	if err := writeStateChunk(state, bessBlockCore, core); err != nil {
+		return err
+	}
+
+	// No memory bank controller nor RTC are emulated, so MBC & RTC blocks are not written
+	if err := writeStateChunk(state, bessBlockEnd, []byte{}); err != nil {
+		return err
+	}
+
+	if err := binary.Write(state, binary.LittleEndian, first); err != nil {
+		return err
+	}
+	_, err := state.WriteString(BESSMagic)
+	return err
+}
+
+// LoadBESS Restore the machine state from a BESS state, as written by this or another emulator.
+// States captured on another model are rejected. The INFO block being optional, states without one can't be checked
+// against the loaded ROM : they are loaded as is.
+func (dmg *DMG) LoadBESS(data []byte) error {
+	if len(data) < 8 || string(data[len(data)-4:]) != BESSMagic {
+		return errors.New("not a BESS save state")
+	}
+
+	offset := binary.LittleEndian.Uint32(data[len(data)-8:])
+	var core *bessCore
+	for {
+		if uint64(offset)+8 > uint64(len(data)) {
+			return errors.New("truncated BESS block")
+		}
+		id := string(data[offset : offset+4])
+		length := binary.LittleEndian.Uint32(data[offset+4:])
+		start := uint64(offset) + 8
+		if start+uint64(length) > uint64(len(data)) {
+			return fmt.Errorf("truncated BESS block %q", id)
+		}
+		block := data[start : start+uint64(length)]
+
+		switch id {
+		case bessBlockInfo:
+			var info bessInfo
+			if err := binary.Read(bytes.NewReader(block), binary.LittleEndian, &info); err != nil {
+				return fmt.Errorf("invalid BESS INFO block: %w", err)
+			}
+			if !bytes.Equal(info.Title[:], dmg.Memory[CartridgeHeaderTitle:CartridgeHeaderTitle+16]) ||
+				!bytes.Equal(info.GlobalChecksum[:], dmg.Memory[CartridgeHeaderGlobalChecksum:CartridgeHeaderGlobalChecksum+2]) {
+				return fmt.Errorf("%w (state title %q)", ErrStateROMMismatch, bytes.TrimRight(info.Title[:], "\x00"))
+			}
+		case bessBlockCore:
+			core = &bessCore{}
+			if err := binary.Read(bytes.NewReader(block), binary.LittleEndian, core); err != nil {
+				return fmt.Errorf("invalid BESS CORE block: %w", err)
+			}
+			if core.MajorVersion != BESSMajorVersion {
+				return fmt.Errorf("unsupported BESS version %d.%d", core.MajorVersion, core.MinorVersion)
+			}
+		case bessBlockMBC, bessBlockRTC:
+			// No memory bank controller nor RTC are emulated yet
+		}
+
+		if id == bessBlockEnd {
+			break
+		}
+		offset = uint32(start) + length
+	}
+
+	if core == nil {
+		return errors.New("BESS save state is missing the CORE block")
+	}
+	if model, ok := bessModel(core.Model); ok && model != dmg.Model {
+		return fmt.Errorf("save state was captured on a %s, the emulated model is %s", model, dmg.Model)
+	}
+
+	// Check every buffer before touching the machine
+	buffers := []struct {
+		buffer bessBuffer
+		start  uint16
+		size   int
+	}{
+		{core.RAM, WRAMStart, WRAMEnd - WRAMStart + 1},
+		{core.VRAM, VRAMStart, VRAMEnd - VRAMStart + 1},
+		{core.MBCRAM, ExternalRAMStart, ExternalRAMEnd - ExternalRAMStart + 1},
+		{core.OAM, OAMStart, OAMEnd - OAMStart + 1},
+		{core.HRAM, HRAMStart, HRAMEnd - HRAMStart},
+	}
+	for _, b := range buffers {
+		if uint64(b.buffer.Offset)+uint64(b.buffer.Size) > uint64(len(data)) {
+			return errors.New("BESS memory buffer out of the file bounds")
+		}
+	}
+
+	dmg.Gbz80.Pc = core.Pc
+	dmg.Gbz80.Af = core.Af
+	dmg.Gbz80.Bc = core.Bc
+	dmg.Gbz80.De = core.De
+	dmg.Gbz80.Hl = core.Hl
+	dmg.Gbz80.Sp = core.Sp
+	dmg.Gbz80.Ime = core.Ime != 0
+	dmg.Gbz80.Halted = core.ExecutionState != bessRunning
+	copy(dmg.Memory[IOPortStart:IOPortEnd+1], core.IO[:])
+	dmg.Memory[InterruptEnableReg] = core.Ie
+	dmg.BootROMMapped = len(dmg.BootROM) > 0 && core.IO[BootROMDisableReg-IOPortStart] == 0
+
+	for _, b := range buffers {
+		// CGB states have banked WRAM & VRAM, only the first bank can be mapped here
+		size := min(int(b.buffer.Size), b.size)
+		copy(dmg.Memory[b.start:int(b.start)+size], data[b.buffer.Offset:])
+	}
+	dmg.forgetBreak()
+
+	return nil
+}
+
+// bessModel Get the model matching a BESS model identifier, only family & model are significant
+// except for the early DMG revision
+func bessModel(id [4]byte) (Model, bool) {
+	switch string(id[:2]) {
+	case "GD":
+		if id[2] == '0' {
+			return ModelDMG0, true
+		}
+		return ModelDMG, true
+	case "GM":
+		return ModelMGB, true
+	case "SN", "SP":
+		return ModelSGB, true
+	case "S2":
+		return ModelSGB2, true
+	case "CC":
+		return ModelCGB, true
+	case "CA":
+		return ModelAGB, true
+	}
+	return ModelDMG, false
+}
+
+// cartridgeRAMSize External RAM size declared by the cartridge header, limited to the mapped 8KB
+func (dmg *DMG) cartridgeRAMSize() uint32 {
+	if dmg.Memory[CartridgeHeaderRamSize] == 0 {
+		return 0
+	}
+	return ExternalRAMEnd - ExternalRAMStart + 1
+}
@@ -0,0 +1,89 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestBESSRoundTrip(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	dmg.Model = ModelMGB
+	dmg.Gbz80.SetR16Register(R16_HL, 0xBEEF)
+	dmg.Gbz80.Halted = true
+	dmg.SetMemoryU8(WRAMStart, 0x11)
+	dmg.SetMemoryU8(VRAMStart+0x100, 0x22)
+	dmg.SetMemoryU8(HRAMStart+1, 0x33)
+	dmg.SetMemoryU8(InterruptEnableReg, 0x05)
+
+	var state bytes.Buffer
+	if err := dmg.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	other := makeTestDMG(t, 0x00)
+	if err := other.LoadBESS(state.Bytes()); err == nil {
+		t.Error("expected a state captured on another model to be rejected")
+	}
+	other.Model = ModelMGB
+	if err := other.LoadBESS(state.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if other.Gbz80.Hl != 0xBEEF || !other.Gbz80.Halted {
+		t.Error("expected CPU state to be restored from the CORE block")
+	}
+	if other.Memory[WRAMStart] != 0x11 || other.Memory[VRAMStart+0x100] != 0x22 || other.Memory[HRAMStart+1] != 0x33 {
+		t.Error("expected memory to be restored from the CORE block buffers")
+	}
+	if other.Memory[InterruptEnableReg] != 0x05 {
+		t.Error("expected IE to be restored")
+	}
+}
+
+// TestLoadStateBESSOnly Load a state holding BESS blocks only, as written by other emulators
+func TestLoadStateBESSOnly(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	wram := bytes.Repeat([]uint8{0x77}, 0x2000)
+
+	var state bytes.Buffer
+	state.Write(wram)
+	first := uint32(state.Len())
+	core := bessCore{
+		MajorVersion: 1,
+		MinorVersion: 1,
+		Pc:           0x0150,
+		Sp:           0xDFF0,
+		RAM:          bessBuffer{0x2000, 0},
+	}
+	copy(core.Model[:], "GDB ")
+	if err := writeStateChunk(&state, bessBlockCore, core); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStateChunk(&state, bessBlockEnd, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	_ = binary.Write(&state, binary.LittleEndian, first)
+	state.WriteString(BESSMagic)
+
+	if err := dmg.LoadState(&state); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Gbz80.Pc != 0x0150 || dmg.Gbz80.Sp != 0xDFF0 {
+		t.Error("expected registers from the CORE block")
+	}
+	if dmg.Memory[WRAMStart+0x1000] != 0x77 {
+		t.Error("expected WRAM to be loaded from the RAM buffer")
+	}
+}
+
+func TestLoadBESSFromOtherROM(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	var state bytes.Buffer
+	if err := dmg.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	if err := makeTestDMG(t, 0x41).LoadBESS(state.Bytes()); !errors.Is(err, ErrStateROMMismatch) {
+		t.Errorf("expected ErrStateROMMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package emulator
+
+import (
+	"fmt"
+	"os"
+)
+
+// Boot ROM sizes & registers
+// See : https://gbdev.io/pandocs/Power_Up_Sequence.html
+const (
+	DMGBootROMSize    = 0x100 // DMG, MGB & SGB boot ROMs
+	CGBBootROMSize    = 0x900 // CGB & AGB boot ROMs (0x100-0x1FF is left to the cartridge header)
+	BootROMDisableReg = 0xFF50
+)
+
+// postBootIO Hardware registers values after the DMG boot ROM handed over to the cartridge
+var postBootIO = map[uint16]uint8{
+	0xFF00: 0xCF, // P1
+	0xFF01: 0x00, // SB
+	0xFF02: 0x7E, // SC
+	0xFF04: 0xAB, // DIV
+	0xFF05: 0x00, // TIMA
+	0xFF06: 0x00, // TMA
+	0xFF07: 0xF8, // TAC
+	0xFF0F: 0xE1, // IF
+	0xFF10: 0x80, // NR10
+	0xFF11: 0xBF, // NR11
+	0xFF12: 0xF3, // NR12
+	0xFF13: 0xFF, // NR13
+	0xFF14: 0xBF, // NR14
+	0xFF16: 0x3F, // NR21
+	0xFF17: 0x00, // NR22
+	0xFF18: 0xFF, // NR23
+	0xFF19: 0xBF, // NR24
+	0xFF1A: 0x7F, // NR30
+	0xFF1B: 0xFF, // NR31
+	0xFF1C: 0x9F, // NR32
+	0xFF1D: 0xFF, // NR33
+	0xFF1E: 0xBF, // NR34
+	0xFF20: 0xFF, // NR41
+	0xFF21: 0x00, // NR42
+	0xFF22: 0x00, // NR43
+	0xFF23: 0xBF, // NR44
+	0xFF24: 0x77, // NR50
+	0xFF25: 0xF3, // NR51
+	0xFF26: 0xF1, // NR52
+	0xFF40: 0x91, // LCDC
+	0xFF41: 0x85, // STAT
+	0xFF42: 0x00, // SCY
+	0xFF43: 0x00, // SCX
+	0xFF44: 0x00, // LY
+	0xFF45: 0x00, // LYC
+	0xFF46: 0xFF, // DMA
+	0xFF47: 0xFC, // BGP
+	0xFF48: 0xFF, // OBP0 (not initialised by the boot ROM)
+	0xFF49: 0xFF, // OBP1 (not initialised by the boot ROM)
+	0xFF4A: 0x00, // WY
+	0xFF4B: 0x00, // WX
+	0xFF4D: 0xFF, // KEY1
+	0xFF4F: 0xFF, // VBK
+	0xFF50: 0xFF, // Boot ROM disabled
+	0xFF51: 0xFF, // HDMA1
+	0xFF52: 0xFF, // HDMA2
+	0xFF53: 0xFF, // HDMA3
+	0xFF54: 0xFF, // HDMA4
+	0xFF55: 0xFF, // HDMA5
+	0xFF56: 0xFF, // RP
+	0xFF68: 0xFF, // BCPS
+	0xFF69: 0xFF, // BCPD
+	0xFF6A: 0xFF, // OCPS
+	0xFF6B: 0xFF, // OCPD
+	0xFF70: 0xFF, // SVBK
+	0xFFFF: 0x00, // IE
+}
+
+// LoadBootROM Load a boot ROM from path & map it over the cartridge until 0xFF50 is written.
+// The CPU is reset so that execution starts at the beginning of the boot ROM.
+func (dmg *DMG) LoadBootROM(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(data) != DMGBootROMSize && len(data) != CGBBootROMSize {
+		return fmt.Errorf("invalid boot ROM size: %d bytes (expected %d or %d)", len(data), DMGBootROMSize, CGBBootROMSize)
+	}
+
+	dmg.BootROM = data
+	dmg.BootROMMapped = true
+	dmg.Gbz80.Reset()
+
+	return nil
+}
+
+// isBootROMAddress Is address currently served by the boot ROM instead of the cartridge
+func (dmg *DMG) isBootROMAddress(address uint16) bool {
+	if !dmg.BootROMMapped {
+		return false
+	}
+	if address < DMGBootROMSize {
+		return true
+	}
+	return len(dmg.BootROM) == CGBBootROMSize && address >= 0x200 && address < CGBBootROMSize
+}
+
+// SkipBoot Put the CPU & hardware registers in the state the boot ROM of the selected model leaves them in,
+// execution resumes at the cartridge entry point.
+func (dmg *DMG) SkipBoot() {
+	dmg.BootROMMapped = false
+
+	af, bc, de, hl := dmg.postBootRegisters()
+	dmg.Gbz80.Af = af
+	dmg.Gbz80.Bc = bc
+	dmg.Gbz80.De = de
+	dmg.Gbz80.Hl = hl
+	dmg.Gbz80.Sp = 0xFFFE
+	dmg.Gbz80.Pc = CartridgeHeaderEntryPoint
+	dmg.Gbz80.Ime = false
+	dmg.Gbz80.Halted = false
+
+	for address, value := range postBootIO {
+		dmg.Memory[address] = value
+	}
+
+	// Model specific registers
+	switch dmg.Model {
+	case ModelDMG0:
+		dmg.Memory[0xFF04] = 0x18
+		dmg.Memory[0xFF41] = 0x81
+	case ModelSGB, ModelSGB2:
+		dmg.Memory[0xFF04] = 0x00
+		dmg.Memory[0xFF26] = 0xF0
+	case ModelCGB, ModelAGB:
+		dmg.Memory[0xFF02] = 0x7F
+		dmg.Memory[0xFF04] = 0x00
+		dmg.Memory[0xFF46] = 0x00
+		if dmg.CGBMode() {
+			dmg.Memory[0xFF4D] = 0x7E
+			dmg.Memory[0xFF4F] = 0xFE
+			dmg.Memory[0xFF56] = 0x3E
+			dmg.Memory[0xFF70] = 0xF8
+		}
+	}
+}
+
+// postBootRegisters Compute AF, BC, DE & HL values left by the boot ROM of the selected model.
+// See : https://gbdev.io/pandocs/Power_Up_Sequence.html#cpu-registers
+func (dmg *DMG) postBootRegisters() (af, bc, de, hl uint16) {
+	// DMG & MGB boot ROMs leave H & C flags set unless the header checksum is 0
+	var dmgFlags uint16 = FLAG_Z
+	if dmg.Memory[CartridgeHeaderHeaderChecksum] != 0 {
+		dmgFlags |= FLAG_H | FLAG_C
+	}
+
+	switch dmg.Model {
+	case ModelDMG0:
+		return 0x0100, 0xFF13, 0x00C1, 0x8403
+	case ModelMGB:
+		return 0xFF00 | dmgFlags, 0x0013, 0x00D8, 0x014D
+	case ModelSGB:
+		return 0x0100, 0x0014, 0x0000, 0xC060
+	case ModelSGB2:
+		return 0xFF00, 0x0014, 0x0000, 0xC060
+	case ModelCGB, ModelAGB:
+		af, bc, de, hl = 0x1180, 0x0000, 0xFF56, 0x000D
+		if !dmg.CGBMode() {
+			// DMG compatibility mode, B holds the title checksum used to pick a palette
+			b := dmg.compatibilityTitleChecksum()
+			bc = uint16(b) << 8
+			de = 0x0008
+			hl = 0x007C
+			if b == 0x43 || b == 0x58 {
+				hl = 0x991A
+			}
+		}
+		if dmg.Model == ModelAGB {
+			// AGB boot ROM ends with an INC B, used by games to detect the GBA
+			b := uint8(bc>>8) + 1
+			bc = (bc & 0x00FF) | uint16(b)<<8
+			af = af & 0xFF00
+			if b == 0 {
+				af |= FLAG_Z
+			}
+			if b&0x0F == 0 {
+				af |= FLAG_H
+			}
+		}
+		return af, bc, de, hl
+	default:
+		return 0x0100 | dmgFlags, 0x0013, 0x00D8, 0x014D
+	}
+}
+
+// compatibilityTitleChecksum Sum of the title bytes, computed by the CGB boot ROM for Nintendo licensed games only
+func (dmg *DMG) compatibilityTitleChecksum() uint8 {
+	oldLicensee := dmg.Memory[CartridgeHeaderOldLicenseeCode]
+	nintendo := oldLicensee == 0x01 ||
+		(oldLicensee == 0x33 && dmg.Memory[CartridgeHeaderNewLicenseeCode] == '0' && dmg.Memory[CartridgeHeaderNewLicenseeCode+1] == '1')
+	if !nintendo {
+		return 0
+	}
+	var sum uint8
+	for i := CartridgeHeaderTitle; i < CartridgeHeaderTitle+16; i++ {
+		sum += dmg.Memory[i]
+	}
+	return sum
+}
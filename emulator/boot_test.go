@@ -0,0 +1,86 @@
+package emulator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSkipBootDMG(t *testing.T) {
+	dmg := MakeDMG()
+	dmg.Memory[CartridgeHeaderHeaderChecksum] = 0x42
+	dmg.SkipBoot()
+
+	if dmg.Gbz80.Af != 0x01B0 {
+		t.Errorf("expected AF to be 0x01B0, but was 0x%04X", dmg.Gbz80.Af)
+	}
+	if dmg.Gbz80.Bc != 0x0013 || dmg.Gbz80.De != 0x00D8 || dmg.Gbz80.Hl != 0x014D {
+		t.Errorf("unexpected BC/DE/HL : 0x%04X 0x%04X 0x%04X", dmg.Gbz80.Bc, dmg.Gbz80.De, dmg.Gbz80.Hl)
+	}
+	if dmg.Gbz80.Sp != 0xFFFE || dmg.Gbz80.Pc != CartridgeHeaderEntryPoint {
+		t.Errorf("unexpected SP/PC : 0x%04X 0x%04X", dmg.Gbz80.Sp, dmg.Gbz80.Pc)
+	}
+	if dmg.Memory[0xFF40] != 0x91 || dmg.Memory[0xFF04] != 0xAB {
+		t.Error("expected LCDC & DIV to hold their post boot values")
+	}
+}
+
+func TestSkipBootDMGZeroHeaderChecksum(t *testing.T) {
+	dmg := MakeDMG()
+	dmg.SkipBoot()
+	if dmg.Gbz80.Af != 0x0180 {
+		t.Errorf("expected AF to be 0x0180, but was 0x%04X", dmg.Gbz80.Af)
+	}
+}
+
+func TestSkipBootAGB(t *testing.T) {
+	dmg := MakeDMG()
+	dmg.Model = ModelAGB
+	dmg.Memory[CartridgeHeaderCGBFlag] = 0x80
+	dmg.SkipBoot()
+	if dmg.Gbz80.A() != 0x11 || dmg.Gbz80.B() != 0x01 || dmg.Gbz80.F() != 0x00 {
+		t.Errorf("unexpected AGB registers A=0x%02X B=0x%02X F=0x%02X", dmg.Gbz80.A(), dmg.Gbz80.B(), dmg.Gbz80.F())
+	}
+	if dmg.Memory[0xFF70] != 0xF8 {
+		t.Error("expected SVBK to be initialised in CGB mode")
+	}
+}
+
+func TestBootROMOverlay(t *testing.T) {
+	dmg := MakeDMG()
+	dmg.Memory[0x0000] = 0xAA
+	dmg.Memory[0x0100] = 0xBB
+
+	boot := make([]uint8, DMGBootROMSize)
+	boot[0] = 0x31
+	path := filepath.Join(t.TempDir(), "dmg_boot.bin")
+	if err := os.WriteFile(path, boot, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dmg.LoadBootROM(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if dmg.GetMemoryU8(0x0000) != 0x31 {
+		t.Error("expected boot ROM to be mapped at 0x0000")
+	}
+	if dmg.GetMemoryU8(0x0100) != 0xBB {
+		t.Error("expected cartridge to be visible at 0x0100")
+	}
+
+	dmg.SetMemoryU8(BootROMDisableReg, 0x01)
+	if dmg.GetMemoryU8(0x0000) != 0xAA {
+		t.Error("expected cartridge to be visible at 0x0000 once the boot ROM is unmapped")
+	}
+}
+
+func TestLoadBootROMInvalidSize(t *testing.T) {
+	dmg := MakeDMG()
+	path := filepath.Join(t.TempDir(), "boot.bin")
+	if err := os.WriteFile(path, make([]uint8, 12), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dmg.LoadBootROM(path); err == nil {
+		t.Error("expected an error for an invalid boot ROM size")
+	}
+}
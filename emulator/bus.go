@@ -0,0 +1,8 @@
+package emulator
+
+// BusAccess Memory access made by the CPU, as seen on the bus
+type BusAccess struct {
+	Address uint16
+	Value   uint8
+	Write   bool
+}
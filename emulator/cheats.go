@@ -0,0 +1,194 @@
+package emulator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Cheat codes, as the Game Genie & GameShark devices plugged between the console & the cartridge apply them.
+// See : https://gbdev.io/pandocs/Shark_Cheats.html
+//
+// Game Genie codes "ABC-DEF-GHI" patch ROM reads : the byte at address (F^0xF)<<12 | C<<8 | D<<4 | E reads as AB,
+// only when the ROM holds the compare byte ((G<<4 | I) rotated right by 2, XOR 0xBA) there. 6 digits codes have no compare byte.
+//
+// GameShark codes "TTVVLLHH" write VV at HHLL at the start of every VBlank, HHLL being in cartridge, work or high RAM.
+// TT selects the RAM bank : 00 & 01 write whatever bank is mapped, 8X writes only when cartridge RAM bank X is mapped,
+// 9X only when CGB work RAM bank X is mapped at D000-DFFF.
+//
+// Cheat files list a code per line, enabled or not, with an optional name :
+//
+//	# Super Mario Land
+//	on  01FF16D0 Infinite lives
+//	off 00A-17B-C49 Moon jump
+
+// CheatKind Device a cheat code is written for
+type CheatKind uint8
+
+const (
+	CheatGameGenie CheatKind = iota
+	CheatGameShark
+)
+
+// Cheat Parsed cheat code
+type Cheat struct {
+	Code       string // As entered, identifying the cheat
+	Name       string
+	Enabled    bool
+	Kind       CheatKind
+	Address    uint16
+	Value      uint8
+	Compare    uint8 // Game Genie byte expected in ROM
+	HasCompare bool
+	Bank       uint8 // GameShark RAM bank selector
+}
+
+// ParseCheat Parse a Game Genie ("ABC-DEF-GHI", "ABC-DEF") or GameShark ("01FF16D0") code, disabled
+func ParseCheat(code string) (Cheat, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	digits := strings.ReplaceAll(code, "-", "")
+	v, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return Cheat{}, fmt.Errorf("invalid cheat code %q", code)
+	}
+	c := Cheat{Code: code}
+	switch len(digits) {
+	case 8:
+		c.Kind = CheatGameShark
+		c.Bank = uint8(v >> 24)
+		c.Value = uint8(v >> 16)
+		c.Address = uint16(v&0xFF)<<8 | uint16(v>>8&0xFF)
+		if c.Bank > 0x01 && c.Bank&0xE0 != 0x80 {
+			return Cheat{}, fmt.Errorf("invalid GameShark code %q: unknown bank 0x%02X", code, c.Bank)
+		}
+		if (c.Address < ExternalRAMStart || c.Address > WRAMEnd) && (c.Address < HRAMStart || c.Address == InterruptEnableReg) {
+			return Cheat{}, fmt.Errorf("invalid GameShark code %q: address 0x%04X is out of RAM", code, c.Address)
+		}
+	case 6, 9:
+		c.Kind = CheatGameGenie
+		d := func(i int) uint16 {
+			n, _ := strconv.ParseUint(digits[i:i+1], 16, 8)
+			return uint16(n)
+		}
+		c.Value = uint8(d(0)<<4 | d(1))
+		c.Address = (d(5)^0xF)<<12 | d(2)<<8 | d(3)<<4 | d(4)
+		if c.Address >= 0x8000 {
+			return Cheat{}, fmt.Errorf("invalid Game Genie code %q: address 0x%04X is out of ROM", code, c.Address)
+		}
+		if len(digits) == 9 {
+			cmp := uint8(d(6)<<4 | d(8))
+			c.Compare = (cmp>>2 | cmp<<6) ^ 0xBA
+			c.HasCompare = true
+		}
+	default:
+		return Cheat{}, fmt.Errorf("invalid cheat code %q: expected ABC-DEF-GHI, ABC-DEF or TTVVLLHH", code)
+	}
+	return c, nil
+}
+
+// ReadCheats Read a cheat file
+func ReadCheats(r io.Reader) ([]Cheat, error) {
+	var cheats []Cheat
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		state := strings.ToLower(fields[0])
+		if (state != "on" && state != "off") || len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected on|off <code> [name]", line)
+		}
+		code, name, _ := strings.Cut(strings.TrimSpace(fields[1]), " ")
+		c, err := ParseCheat(code)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		c.Name = strings.TrimSpace(name)
+		c.Enabled = state == "on"
+		cheats = append(cheats, c)
+	}
+	return cheats, scanner.Err()
+}
+
+// WriteCheats Write a cheat file
+func WriteCheats(w io.Writer, cheats []Cheat) error {
+	for _, c := range cheats {
+		state := "off"
+		if c.Enabled {
+			state = "on"
+		}
+		line := strings.TrimSpace(fmt.Sprintf("%-3s %s %s", state, c.Code, c.Name))
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cheatEngine Enabled cheats, indexed for the bus
+type cheatEngine struct {
+	genie map[uint16][]Cheat // Game Genie codes by patched address
+	shark []Cheat
+}
+
+// SetCheats Replace the cheat list, only enabled cheats being applied
+func (dmg *DMG) SetCheats(cheats []Cheat) {
+	dmg.cheatList = append([]Cheat(nil), cheats...)
+	engine := &cheatEngine{genie: map[uint16][]Cheat{}}
+	for _, c := range cheats {
+		switch {
+		case !c.Enabled:
+		case c.Kind == CheatGameGenie:
+			engine.genie[c.Address] = append(engine.genie[c.Address], c)
+		default:
+			engine.shark = append(engine.shark, c)
+		}
+	}
+	dmg.cheats = nil
+	if len(engine.genie) > 0 || len(engine.shark) > 0 {
+		dmg.cheats = engine
+	}
+}
+
+// Cheats Get the cheat list
+func (dmg *DMG) Cheats() []Cheat {
+	return append([]Cheat(nil), dmg.cheatList...)
+}
+
+// patchROMRead Get the value read at address in ROM, once patched by Game Genie codes
+func (e *cheatEngine) patchROMRead(address uint16, value uint8) uint8 {
+	for _, c := range e.genie[address] {
+		if !c.HasCompare || c.Compare == value {
+			return c.Value
+		}
+	}
+	return value
+}
+
+// applyGameShark Write GameShark values, called on VBlank
+func (dmg *DMG) applyGameShark() {
+	for _, c := range dmg.cheats.shark {
+		if c.Bank > 0x01 && c.Bank&0x0F != dmg.mappedRAMBank(c.Bank&0xF0) {
+			continue
+		}
+		dmg.Memory[c.Address] = c.Value
+	}
+}
+
+// mappedRAMBank Bank mapped in cartridge RAM (0x80) or CGB work RAM (0x90).
+// Cartridge RAM banking isn't emulated, so its bank 0 is always mapped.
+func (dmg *DMG) mappedRAMBank(area uint8) uint8 {
+	if area == 0x90 && dmg.CGBMode() {
+		return max(dmg.Memory[0xFF70]&0x07, 1) // SVBK
+	}
+	if area == 0x90 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package emulator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// encodeGameGenie Build the Game Genie code replacing compare at address by value
+func encodeGameGenie(address uint16, value, compare uint8) string {
+	cmp := compare ^ 0xBA
+	cmp = cmp<<2 | cmp>>6
+	return fmt.Sprintf("%02X%X-%02X%X-%X0%X", value, address>>8&0xF, address&0xFF, address>>12^0xF, cmp>>4, cmp&0xF)
+}
+
+func TestParseCheat(t *testing.T) {
+	c, err := ParseCheat("01ff16d0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Kind != CheatGameShark || c.Bank != 0x01 || c.Value != 0xFF || c.Address != 0xD016 || c.Code != "01FF16D0" {
+		t.Errorf("unexpected GameShark cheat %+v", c)
+	}
+
+	code := encodeGameGenie(0x4A53, 0x12, 0x3C)
+	c, err = ParseCheat(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Kind != CheatGameGenie || c.Address != 0x4A53 || c.Value != 0x12 || !c.HasCompare || c.Compare != 0x3C {
+		t.Errorf("unexpected Game Genie cheat %s %+v", code, c)
+	}
+	c, err = ParseCheat(code[:7])
+	if err != nil || c.HasCompare || c.Address != 0x4A53 {
+		t.Errorf("unexpected Game Genie cheat without compare byte %+v, %v", c, err)
+	}
+
+	for _, invalid := range []string{"", "01FF16D", "XYZ-123-456", "21FF16D0", "00A-177-C49", "01FF0100", "01FF0080", "01FF00FE", "01FFFFFF"} {
+		if _, err := ParseCheat(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCheats(t *testing.T) {
+	dmg := makeProgramDMG(t, 0x18, 0xFE)
+	dmg.Memory[0x4000] = 0x3C
+	patch, _ := ParseCheat(encodeGameGenie(0x4000, 0x99, 0x3C))
+	mismatch, _ := ParseCheat(encodeGameGenie(0x4001, 0x99, 0x3C))
+	shark, _ := ParseCheat("0142A0C0")
+	otherBank, _ := ParseCheat("9342A1C0")
+	patch.Enabled, mismatch.Enabled, shark.Enabled, otherBank.Enabled = true, true, true, true
+	dmg.SetCheats([]Cheat{patch, mismatch, shark, otherBank})
+
+	if v := dmg.GetMemoryU8(0x4000); v != 0x99 {
+		t.Errorf("expected the Game Genie code to patch the ROM read, got 0x%02X", v)
+	}
+	if v := dmg.GetMemoryU8(0x4001); v != 0x00 {
+		t.Errorf("expected the Game Genie code to be ignored when the compare byte differs, got 0x%02X", v)
+	}
+	if dmg.Memory[0x4000] != 0x3C {
+		t.Error("the ROM shouldn't be modified")
+	}
+
+	dmg.RunFrame()
+	if dmg.Memory[0xC0A0] != 0x42 {
+		t.Errorf("expected the GameShark code to write RAM on VBlank, got 0x%02X", dmg.Memory[0xC0A0])
+	}
+	if dmg.Memory[0xC0A1] != 0x00 {
+		t.Error("GameShark codes for a work RAM bank that isn't mapped shouldn't write")
+	}
+	dmg.Memory[0xC0A0] = 0x00
+	dmg.RunFrame()
+	if dmg.Memory[0xC0A0] != 0x42 {
+		t.Error("expected the GameShark code to write RAM every frame")
+	}
+
+	shark.Enabled = false
+	patch.Enabled = false
+	dmg.SetCheats([]Cheat{patch, shark})
+	dmg.Memory[0xC0A0] = 0x00
+	dmg.RunFrame()
+	if dmg.Memory[0xC0A0] != 0x00 || dmg.GetMemoryU8(0x4000) != 0x3C || len(dmg.Cheats()) != 2 {
+		t.Error("disabled cheats shouldn't be applied")
+	}
+}
+
+func TestCheatFile(t *testing.T) {
+	file := "# Test\non  01FF16D0 Infinite lives\n\noff 00A-17B-C49\n"
+	cheats, err := ReadCheats(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cheats) != 2 || !cheats[0].Enabled || cheats[0].Name != "Infinite lives" || cheats[1].Enabled || cheats[1].Code != "00A-17B-C49" {
+		t.Fatalf("unexpected cheats %+v", cheats)
+	}
+	var buf bytes.Buffer
+	if err := WriteCheats(&buf, cheats); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "on  01FF16D0 Infinite lives\noff 00A-17B-C49\n" {
+		t.Errorf("unexpected cheat file %q", buf.String())
+	}
+	if _, err := ReadCheats(strings.NewReader("maybe 01FF16D0\n")); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+}
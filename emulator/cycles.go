@@ -0,0 +1,51 @@
+package emulator
+
+// Instructions timing for the GBZ80 CPU
+// See : https://gbdev.io/gb-opcodes/optables/
+
+const (
+	CyclesPerFrame = 70224 // 154 scanlines of 456 T-cycles
+)
+
+// opcodeCycles T-cycles taken by unprefixed instructions.
+// Conditional jumps, calls & returns are given for the not taken case, as the CPU doesn't report branching yet.
+var opcodeCycles = [256]uint8{
+	//  0  1   2   3   4   5   6   7   8   9   A   B   C   D   E   F
+	4, 12, 8, 8, 4, 4, 8, 4, 20, 8, 8, 8, 4, 4, 8, 4, // 0x
+	4, 12, 8, 8, 4, 4, 8, 4, 12, 8, 8, 8, 4, 4, 8, 4, // 1x
+	8, 12, 8, 8, 4, 4, 8, 4, 8, 8, 8, 8, 4, 4, 8, 4, // 2x
+	8, 12, 8, 8, 12, 12, 12, 4, 8, 8, 8, 8, 4, 4, 8, 4, // 3x
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 4x
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 5x
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 6x
+	8, 8, 8, 8, 8, 8, 4, 8, 4, 4, 4, 4, 4, 4, 8, 4, // 7x
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 8x
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 9x
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // Ax
+	4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // Bx
+	8, 12, 12, 16, 12, 16, 8, 16, 8, 16, 12, 4, 12, 24, 8, 16, // Cx
+	8, 12, 12, 4, 12, 16, 8, 16, 8, 16, 12, 4, 12, 4, 8, 16, // Dx
+	12, 12, 8, 4, 4, 16, 8, 16, 16, 4, 16, 4, 4, 4, 8, 16, // Ex
+	12, 12, 8, 4, 4, 16, 8, 16, 12, 8, 16, 4, 4, 4, 8, 16, // Fx
+}
+
+// cbOpcodeCycles T-cycles taken by CB prefixed instructions, prefix included
+func cbOpcodeCycles(opcode uint8) uint8 {
+	if opcode&0x07 != 6 {
+		// Register operand
+		return 8
+	}
+	if opcode&0xC0 == 0x40 {
+		// BIT b, (HL) only reads memory
+		return 12
+	}
+	return 16
+}
+
+// instructionCycles T-cycles taken by an instruction, CB prefixed ones being given as 0xCBxx
+func instructionCycles(opcode uint16) int {
+	if opcode>>8 == 0xCB {
+		return int(cbOpcodeCycles(uint8(opcode)))
+	}
+	return int(opcodeCycles[opcode])
+}
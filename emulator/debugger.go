@@ -0,0 +1,333 @@
+package emulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Debugger breakpoints stop RunFrame, the instruction hitting one being reported by BreakHit :
+//   - execution breakpoints fire before the instruction at their address executes, in a given ROM bank or any,
+//   - opcode breakpoints fire before an instruction with their opcode executes (LD B,B is a common software breakpoint),
+//   - watchpoints fire on reads, writes or execution within an address range, optionally of a given value.
+//     Read & write watchpoints fire once the accessing instruction completes, reads including operand fetches.
+//
+// Running again from a breakpoint executes the instruction it stopped on.
+//
+// Breakpoints can be parsed from text (see ParseBreakpoint) :
+//
+//	0x0150              execution breakpoint, any bank
+//	1:0x4000            execution breakpoint in ROM bank 1
+//	w 0xC000-0xC0FF     write watchpoint on a range
+//	rw 0xFF80=0x03      read or write watchpoint of value 0x03
+//	x 0x0150-0x015F     execute watchpoint
+//	op 0x40             opcode breakpoint (LD B,B), 0xCB7C for prefixed opcodes
+
+// AnyBank Bank of the breakpoints firing whatever bank is mapped
+const AnyBank = -1
+
+// Access Memory access kinds, combined
+type Access uint8
+
+const (
+	AccessRead Access = 1 << iota
+	AccessWrite
+	AccessExecute
+)
+
+// String Get the access kinds, as "rw"
+func (a Access) String() string {
+	var s strings.Builder
+	for i, c := range "rwx" {
+		if a&(1<<i) != 0 {
+			s.WriteRune(c)
+		}
+	}
+	return s.String()
+}
+
+// BreakKind What a breakpoint fires on
+type BreakKind uint8
+
+const (
+	BreakExec   BreakKind = iota // Program counter reaches Address in Bank
+	BreakWatch                   // Address to End accessed as Access
+	BreakOpcode                  // Instruction Opcode about to execute
+)
+
+// Breakpoint Execution breakpoint, watchpoint or opcode breakpoint
+type Breakpoint struct {
+	ID       int // Set by AddBreakpoint
+	Kind     BreakKind
+	Address  uint16
+	End      uint16 // Last watched address, included
+	Bank     int    // ROM bank of execution breakpoints, AnyBank for all of them
+	Access   Access // Accesses watched
+	Value    uint8  // Value read, written or executed, when HasValue
+	HasValue bool
+	Opcode   uint16 // 0xCBxx for prefixed opcodes
+	Disabled bool
+}
+
+// String Describe the breakpoint, as parsed by ParseBreakpoint
+func (b Breakpoint) String() string {
+	switch b.Kind {
+	case BreakExec:
+		if b.Bank == AnyBank {
+			return fmt.Sprintf("0x%04X", b.Address)
+		}
+		return fmt.Sprintf("%d:0x%04X", b.Bank, b.Address)
+	case BreakOpcode:
+		return fmt.Sprintf("op 0x%02X", b.Opcode)
+	}
+	s := fmt.Sprintf("%s 0x%04X", b.Access, b.Address)
+	if b.End != b.Address {
+		s += fmt.Sprintf("-0x%04X", b.End)
+	}
+	if b.HasValue {
+		s += fmt.Sprintf("=0x%02X", b.Value)
+	}
+	return s
+}
+
+// ParseBreakpoint Parse a breakpoint, the execution breakpoints being in any bank unless given
+func ParseBreakpoint(text string) (Breakpoint, error) {
+	fields := strings.Fields(strings.ToLower(text))
+	b := Breakpoint{Bank: AnyBank}
+	switch {
+	case len(fields) == 1:
+		b.Kind = BreakExec
+		address := fields[0]
+		if bank, a, found := strings.Cut(address, ":"); found {
+			n, err := strconv.ParseUint(bank, 0, 16)
+			if err != nil {
+				return b, fmt.Errorf("invalid bank %q", bank)
+			}
+			b.Bank, address = int(n), a
+		}
+		a, err := parseAddress(address)
+		b.Address, b.End = a, a
+		return b, err
+	case len(fields) == 2 && fields[0] == "op":
+		b.Kind = BreakOpcode
+		op, err := strconv.ParseUint(fields[1], 0, 16)
+		if err != nil || (op > 0xFF && op>>8 != 0xCB) {
+			return b, fmt.Errorf("invalid opcode %q", fields[1])
+		}
+		b.Opcode = uint16(op)
+		return b, nil
+	case len(fields) == 2:
+		b.Kind = BreakWatch
+		for _, c := range fields[0] {
+			i := strings.IndexRune("rwx", c)
+			if i < 0 {
+				return b, fmt.Errorf("invalid access %q, expected r, w, x or a combination", fields[0])
+			}
+			b.Access |= 1 << i
+		}
+		rng, value, hasValue := strings.Cut(fields[1], "=")
+		start, end, isRange := strings.Cut(rng, "-")
+		var err error
+		if b.Address, err = parseAddress(start); err != nil {
+			return b, err
+		}
+		b.End = b.Address
+		if isRange {
+			if b.End, err = parseAddress(end); err != nil {
+				return b, err
+			}
+			if b.End < b.Address {
+				return b, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		if hasValue {
+			v, err := strconv.ParseUint(value, 0, 8)
+			if err != nil {
+				return b, fmt.Errorf("invalid value %q", value)
+			}
+			b.Value, b.HasValue = uint8(v), true
+		}
+		return b, nil
+	}
+	return b, fmt.Errorf("invalid breakpoint %q, expected [bank:]address, r|w|x start[-end][=value] or op opcode", text)
+}
+
+// parseAddress Parse a 16-bit address
+func parseAddress(text string) (uint16, error) {
+	a, err := strconv.ParseUint(text, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+	return uint16(a), nil
+}
+
+// BreakHit Breakpoint fired, and the instruction that fired it
+type BreakHit struct {
+	Breakpoint Breakpoint
+	PC         uint16    // Address of the instruction
+	Access     BusAccess // Memory access firing a read or write watchpoint
+}
+
+// String Describe the hit
+func (h BreakHit) String() string {
+	s := fmt.Sprintf("breakpoint %d (%s) hit at PC=0x%04X", h.Breakpoint.ID, h.Breakpoint, h.PC)
+	if h.Breakpoint.Kind == BreakWatch && h.Breakpoint.Access&(AccessRead|AccessWrite) != 0 && h.Access != (BusAccess{}) {
+		kind := "read"
+		if h.Access.Write {
+			kind = "write"
+		}
+		s += fmt.Sprintf(": %s 0x%02X at 0x%04X", kind, h.Access.Value, h.Access.Address)
+	}
+	return s
+}
+
+// debugger Breakpoints of a DMG, and the state of the last break
+type debugger struct {
+	breakpoints []Breakpoint
+	nextID      int
+	pc          uint16 // Instruction being executed
+	hit         *BreakHit
+	resume      bool // Execution stopped before the instruction at resumePC, which runs without breaking when resumed
+	resumePC    uint16
+}
+
+// AddBreakpoint Add a breakpoint, returning its ID
+func (dmg *DMG) AddBreakpoint(b Breakpoint) int {
+	if dmg.debugger == nil {
+		dmg.debugger = &debugger{nextID: 1}
+	}
+	if b.Kind != BreakWatch {
+		b.End = b.Address
+	}
+	b.ID = dmg.debugger.nextID
+	dmg.debugger.nextID++
+	dmg.debugger.breakpoints = append(dmg.debugger.breakpoints, b)
+	return b.ID
+}
+
+// RemoveBreakpoint Remove the breakpoint id, returning false if there was none
+func (dmg *DMG) RemoveBreakpoint(id int) bool {
+	if dmg.debugger == nil {
+		return false
+	}
+	for i, b := range dmg.debugger.breakpoints {
+		if b.ID == id {
+			dmg.debugger.breakpoints = append(dmg.debugger.breakpoints[:i], dmg.debugger.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetBreakpointEnabled Enable or disable the breakpoint id, returning false if there is none
+func (dmg *DMG) SetBreakpointEnabled(id int, enabled bool) bool {
+	if dmg.debugger == nil {
+		return false
+	}
+	for i := range dmg.debugger.breakpoints {
+		if dmg.debugger.breakpoints[i].ID == id {
+			dmg.debugger.breakpoints[i].Disabled = !enabled
+			return true
+		}
+	}
+	return false
+}
+
+// Breakpoints Get the breakpoints, by ID
+func (dmg *DMG) Breakpoints() []Breakpoint {
+	if dmg.debugger == nil {
+		return nil
+	}
+	return append([]Breakpoint(nil), dmg.debugger.breakpoints...)
+}
+
+// BreakHit Get the breakpoint fired by the last instruction, nil when none did
+func (dmg *DMG) BreakHit() *BreakHit {
+	if dmg.debugger == nil || dmg.debugger.hit == nil {
+		return nil
+	}
+	hit := *dmg.debugger.hit
+	return &hit
+}
+
+// forgetBreak Forget the last break, once the machine state is replaced : running again mustn't skip a breakpoint
+// because the new state happens to stop where the last break did
+func (dmg *DMG) forgetBreak() {
+	if dmg.debugger != nil {
+		dmg.debugger.hit = nil
+		dmg.debugger.resume = false
+	}
+}
+
+// breakBeforeInstruction Check execution & opcode breakpoints on the instruction at PC, true when it mustn't execute
+func (d *debugger) breakBeforeInstruction(dmg *DMG) bool {
+	pc := dmg.Gbz80.Pc
+	d.pc = pc
+	d.hit = nil
+	if d.resume {
+		d.resume = false
+		if pc == d.resumePC {
+			return false
+		}
+	}
+
+	first := dmg.readMemoryU8(pc)
+	opcode := uint16(first)
+	if first == 0xCB {
+		opcode = 0xCB00 | uint16(dmg.readMemoryU8(pc+1))
+	}
+	for _, b := range d.breakpoints {
+		var fired bool
+		switch {
+		case b.Disabled:
+		case b.Kind == BreakExec:
+			fired = b.Address == pc && (b.Bank == AnyBank || b.Bank == dmg.bankAt(pc))
+		case b.Kind == BreakOpcode:
+			fired = b.Opcode == opcode
+		case b.Access&AccessExecute != 0:
+			fired = pc >= b.Address && pc <= b.End && (!b.HasValue || b.Value == first)
+		}
+		if fired {
+			d.hit = &BreakHit{Breakpoint: b, PC: pc}
+			d.resume, d.resumePC = true, pc
+			return true
+		}
+	}
+	return false
+}
+
+// watchAccess Check read & write watchpoints on a memory access, the first one fired being kept
+func (d *debugger) watchAccess(access BusAccess) {
+	if d.hit != nil {
+		return
+	}
+	kind := AccessRead
+	if access.Write {
+		kind = AccessWrite
+	}
+	for _, b := range d.breakpoints {
+		if b.Disabled || b.Kind != BreakWatch || b.Access&kind == 0 || access.Address < b.Address || access.Address > b.End {
+			continue
+		}
+		if b.HasValue && b.Value != access.Value {
+			continue
+		}
+		d.hit = &BreakHit{Breakpoint: b, PC: d.pc, Access: access}
+		return
+	}
+}
+
+// bankAt Bank mapped at address. Without memory bank controllers, ROM bank 1 is always the one at 4000-7FFF.
+func (dmg *DMG) bankAt(address uint16) int {
+	switch {
+	case address < 0x4000:
+		return 0
+	case address < 0x8000:
+		return 1
+	case address >= 0xA000 && address < 0xC000:
+		return int(dmg.mappedRAMBank(0x80))
+	case address >= 0xD000 && address < 0xE000:
+		return int(dmg.mappedRAMBank(0x90))
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package emulator
+
+import (
+	"bytes"
+	"testing"
+)
+
+// debugProgram Count in HRAM then loop, with a software breakpoint
+var debugProgram = []uint8{
+	0x3E, 0x2A, // 0x0100 LD A, 0x2A
+	0xE0, 0x80, // 0x0102 LDH (0x80), A
+	0x40,       // 0x0104 LD B, B
+	0x18, 0xFE, // 0x0105 JR -2
+}
+
+// runUntilBreak Run frames until a breakpoint fires
+func runUntilBreak(t *testing.T, dmg *DMG) *BreakHit {
+	t.Helper()
+	for range 3 {
+		dmg.RunFrame()
+		if hit := dmg.BreakHit(); hit != nil {
+			return hit
+		}
+	}
+	t.Fatal("expected a breakpoint to fire")
+	return nil
+}
+
+func TestParseBreakpoint(t *testing.T) {
+	for text, want := range map[string]Breakpoint{
+		"0x0150":          {Kind: BreakExec, Address: 0x0150, End: 0x0150, Bank: AnyBank},
+		"1:0x4000":        {Kind: BreakExec, Address: 0x4000, End: 0x4000, Bank: 1},
+		"w 0xC000-0xC0FF": {Kind: BreakWatch, Address: 0xC000, End: 0xC0FF, Bank: AnyBank, Access: AccessWrite},
+		"RW 0xFF80=0x03":  {Kind: BreakWatch, Address: 0xFF80, End: 0xFF80, Bank: AnyBank, Access: AccessRead | AccessWrite, Value: 0x03, HasValue: true},
+		"op 0xCB7C":       {Kind: BreakOpcode, Opcode: 0xCB7C, Bank: AnyBank},
+		"x 0x0150-0x015F": {Kind: BreakWatch, Address: 0x0150, End: 0x015F, Bank: AnyBank, Access: AccessExecute},
+	} {
+		b, err := ParseBreakpoint(text)
+		if err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if b != want {
+			t.Errorf("%s: got %+v, want %+v", text, b, want)
+		}
+		if again, err := ParseBreakpoint(b.String()); err != nil || again != b {
+			t.Errorf("%s: %q doesn't parse back, %v", text, b.String(), err)
+		}
+	}
+	for _, invalid := range []string{"", "0x10000", "z 0xC000", "w 0xC0FF-0xC000", "op 0x1234", "w 0xC000=0x100"} {
+		if _, err := ParseBreakpoint(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestExecBreakpoint(t *testing.T) {
+	dmg := makeProgramDMG(t, debugProgram...)
+	id := dmg.AddBreakpoint(Breakpoint{Kind: BreakExec, Address: 0x0102, Bank: AnyBank})
+	hit := runUntilBreak(t, dmg)
+	if hit.Breakpoint.ID != id || hit.PC != 0x0102 || dmg.Gbz80.Pc != 0x0102 || dmg.Memory[0xFF80] != 0 {
+		t.Fatalf("expected to stop before 0x0102 executes, got %v at PC=0x%04X", hit, dmg.Gbz80.Pc)
+	}
+
+	// Resuming executes the instruction the breakpoint stopped on
+	dmg.StepInstruction()
+	if dmg.BreakHit() != nil || dmg.Memory[0xFF80] != 0x2A {
+		t.Errorf("expected the instruction to execute when resumed, got %v", dmg.BreakHit())
+	}
+
+	dmg.AddBreakpoint(Breakpoint{Kind: BreakExec, Address: 0x0104, Bank: 1})
+	dmg.AddBreakpoint(Breakpoint{Kind: BreakExec, Address: 0x0104, Bank: 0})
+	dmg.StepInstruction()
+	if hit := dmg.BreakHit(); hit == nil || hit.Breakpoint.Bank != 0 {
+		t.Errorf("expected the bank 0 breakpoint only to fire, got %v", hit)
+	}
+	dmg.RemoveBreakpoint(id)
+	if len(dmg.Breakpoints()) != 2 || dmg.RemoveBreakpoint(id) {
+		t.Error("expected the breakpoint to be removed")
+	}
+}
+
+func TestOpcodeBreakpointAndWatchpoints(t *testing.T) {
+	dmg := makeProgramDMG(t, debugProgram...)
+	op := dmg.AddBreakpoint(Breakpoint{Kind: BreakOpcode, Opcode: 0x40})
+	// Writes of another value don't fire
+	dmg.AddBreakpoint(Breakpoint{Kind: BreakWatch, Address: 0xFF80, End: 0xFF8F, Access: AccessWrite, Value: 0x01, HasValue: true})
+	watch := dmg.AddBreakpoint(Breakpoint{Kind: BreakWatch, Address: 0xFF80, End: 0xFF8F, Access: AccessWrite})
+
+	hit := runUntilBreak(t, dmg)
+	if hit.Breakpoint.ID != watch || hit.PC != 0x0102 || !hit.Access.Write || hit.Access.Value != 0x2A || hit.Access.Address != 0xFF80 {
+		t.Fatalf("expected the write watchpoint to fire, got %v", hit)
+	}
+	if dmg.Gbz80.Pc != 0x0104 {
+		t.Errorf("expected the writing instruction to complete, PC=0x%04X", dmg.Gbz80.Pc)
+	}
+	hit = runUntilBreak(t, dmg)
+	if hit.Breakpoint.ID != op || hit.PC != 0x0104 {
+		t.Fatalf("expected LD B,B to fire the opcode breakpoint, got %v", hit)
+	}
+
+	dmg.SetBreakpointEnabled(op, false)
+	dmg.SetBreakpointEnabled(watch, false)
+	x := dmg.AddBreakpoint(Breakpoint{Kind: BreakWatch, Address: 0x0105, End: 0x0106, Access: AccessExecute, Value: 0x18, HasValue: true})
+	hit = runUntilBreak(t, dmg)
+	if hit.Breakpoint.ID != x || hit.PC != 0x0105 {
+		t.Errorf("expected the execute watchpoint to fire, got %v", hit)
+	}
+	if want := "breakpoint 4 (x 0x0105-0x0106=0x18) hit at PC=0x0105"; hit.String() != want {
+		t.Errorf("got %q, want %q", hit.String(), want)
+	}
+}
+
+func TestBreakpointAfterLoadState(t *testing.T) {
+	dmg := makeProgramDMG(t, debugProgram...)
+	var state bytes.Buffer
+	if err := dmg.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	dmg.AddBreakpoint(Breakpoint{Kind: BreakExec, Address: 0x0100, Bank: AnyBank})
+	dmg.StepInstruction()
+	if dmg.BreakHit() == nil {
+		t.Fatal("expected the breakpoint to fire")
+	}
+
+	// The loaded state stops where the last break did, the breakpoint must fire again
+	if err := dmg.LoadState(bytes.NewReader(state.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.BreakHit() != nil {
+		t.Error("expected loading a state to forget the last break")
+	}
+	dmg.StepInstruction()
+	if dmg.BreakHit() == nil || dmg.Gbz80.Pc != 0x0100 {
+		t.Errorf("expected the breakpoint to fire after loading a state, PC=0x%04X", dmg.Gbz80.Pc)
+	}
+
+	dmg.Reset()
+	dmg.StepInstruction()
+	if dmg.BreakHit() == nil || dmg.Gbz80.Pc != 0x0100 {
+		t.Errorf("expected the breakpoint to fire after a reset, PC=0x%04X", dmg.Gbz80.Pc)
+	}
+}
@@ -17,6 +17,19 @@ func Disassembly(path string, startPos uint16, lines int) string {
 	if err != nil {
 		panic(err)
 	}
+	return disassemble(dmg, startPos, lines)
+}
+
+// Disassemble Dissasemble the memory of the DMG, from startPos.
+// Runs on a copy of the DMG, so the CPU state is left untouched.
+func (dmg *DMG) Disassemble(startPos uint16, lines int) string {
+	scratch := MakeDMGWithOptions(Options{Model: dmg.Model})
+	scratch.Memory = dmg.Memory
+	return disassemble(scratch, startPos, lines)
+}
+
+// disassemble Dissasemble lines instructions from startPos, moving the PC of dmg
+func disassemble(dmg *DMG, startPos uint16, lines int) string {
 	var dissasembly string
 	dissasembly = "CURRENT INSTRUCTIONS ===> "
 
@@ -46,11 +59,8 @@ func DisassembleCurrentInstructions(dmg *DMG) string {
 	// First check for prefix & read opcode
 	isCBPrefixed := false
 	opcode := dmg.Memory[dmg.Gbz80.Pc]
-	var d uint8
-	print(d)
 	if opcode == 0xCB {
 		isCBPrefixed = true
-		d = dmg.Memory[dmg.Gbz80.Pc+1]
 		opcode = dmg.Memory[dmg.Gbz80.Pc+2]
 		dmg.Gbz80.Pc += 2
 	}
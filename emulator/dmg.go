@@ -2,9 +2,8 @@ package emulator
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"os"
+	"path/filepath"
+	"strings"
 )
 
 const (
@@ -13,17 +12,57 @@ const (
 )
 
 type DMG struct {
-	Gbz80  *Gbz80
-	Memory [MemorySize]uint8 // 64KB Memory
-	Screen [ScreenWidth * ScreenHeight]color.RGBA
+	Gbz80         *Gbz80
+	Model         Model
+	Memory        [MemorySize]uint8 // 64KB Memory
+	ROM           []uint8           // Cartridge ROM, as loaded from file
+	ROMPath       string            // File the ROM was loaded from, possibly an archive
+	ROMName       string            // Name of the ROM file, the archived one for archives
+	BootROM       []uint8           // Optional boot ROM, mapped over the cartridge at power on
+	BootROMMapped bool              // Is the boot ROM still mapped (until 0xFF50 is written)
+	Cycles        uint64            // T-cycles elapsed since power on
+	Frame         uint64            // Frames completed since power on
+	LastOpcode    uint16            // Last executed opcode, CB prefixed ones being given as 0xCBxx
+	Trace         bool              // Print executed instructions
+	// OnBusAccess Called on each memory access made through the bus, when set
+	OnBusAccess func(access BusAccess)
+	// OnMovieEnd Called when a movie played back ends, err being a DesyncError when it ended early
+	OnMovieEnd  func(err error)
+	frameCycles int     // T-cycles elapsed in the current frame
+	buttons     Button  // Pressed joypad buttons
+	serial      []uint8 // Bytes sent on the serial port
+	flatMemory  bool    // Bus is plain RAM
+	// pixels Shades output by the PPU (bits 0-1) with their palette (bits 2-3), frames holding their colors
+	pixels [ScreenWidth * ScreenHeight]uint8
+	colors ColorScheme
+	frames frameBuffers
+	rewind *rewindBuffer
+	movie  *movieSession
+	// movieErr Error that ended the last movie played back
+	movieErr error
+
+	// cheatList Cheats as set, cheats only holding the enabled ones (nil without any)
+	cheatList []Cheat
+	cheats    *cheatEngine
+	debugger  *debugger
 }
 
 // MakeDMG Create a new instance of the DMG (Game Boy)
 func MakeDMG() *DMG {
+	return MakeDMGWithOptions(DefaultOptions())
+}
+
+// MakeDMGWithOptions Create a new instance of the Game Boy, emulating the hardware described by options
+func MakeDMGWithOptions(options Options) *DMG {
 	var mem [MemorySize]uint8
 	d := &DMG{
-		Gbz80:  MakeGbz80(),
-		Memory: mem,
+		Gbz80:      MakeGbz80(),
+		Model:      options.Model,
+		Memory:     mem,
+		Trace:      options.Trace,
+		flatMemory: options.FlatMemory,
+		colors:     DefaultColorScheme(options.Model),
+		frames:     newFrameBuffers(),
 	}
 	d.ClearScreen()
 	return d
@@ -54,7 +93,7 @@ func (dmg *DMG) PrintMemorySection(start int, end int) {
 
 // Print the DMG state
 func (dmg *DMG) Print() {
-	fmt.Println("DMG Model :")
+	fmt.Printf("%s Model :\n", dmg.Model)
 	fmt.Println("-----------")
 	fmt.Println("GB Z80 CPU Registers :")
 	dmg.Gbz80.Print()
@@ -68,24 +107,112 @@ func (dmg *DMG) Print() {
 
 }
 
+// LoadROM Load the ROM at path, which can be a .zip or .gz archive
 func (dmg *DMG) LoadROM(path string) error {
-	data, err := os.ReadFile(path)
+	rom, err := ReadROM(path)
 	if err != nil {
 		return err
 	}
+	return dmg.LoadROMFile(rom)
+}
 
-	if len(data) > MemorySize {
-		return fmt.Errorf("ROM too large: %d bytes", len(data))
+// LoadROMFile Load a ROM already read from disk
+func (dmg *DMG) LoadROMFile(rom ROMFile) error {
+	if len(rom.Data) > MemorySize {
+		return fmt.Errorf("ROM too large: %d bytes", len(rom.Data))
 	}
 
-	copy(dmg.Memory[:], data)
+	dmg.ROM = rom.Data
+	dmg.ROMPath = rom.Path
+	dmg.ROMName = rom.Name
+	copy(dmg.Memory[:], rom.Data)
 
 	return nil
 }
 
+// SavePath Path of a file saved next to the ROM, named after it with extension ext (".state", ...)
+func (dmg *DMG) SavePath(ext string) string {
+	name := strings.TrimSuffix(dmg.ROMName, filepath.Ext(dmg.ROMName))
+	return filepath.Join(filepath.Dir(dmg.ROMPath), name+ext)
+}
+
+// Reset Power cycle the console, keeping the loaded ROM & boot ROM
+func (dmg *DMG) Reset() {
+	dmg.Memory = [MemorySize]uint8{}
+	copy(dmg.Memory[:], dmg.ROM)
+	dmg.Cycles = 0
+	dmg.Frame = 0
+	dmg.frameCycles = 0
+	dmg.LastOpcode = 0
+	dmg.serial = nil
+	dmg.forgetBreak()
+	if dmg.rewind != nil {
+		// Snapshots belong to the previous run
+		dmg.EnableRewind(RewindOptions{Interval: dmg.rewind.interval, Budget: dmg.rewind.budget})
+	}
+	if len(dmg.BootROM) > 0 {
+		dmg.Gbz80.Reset()
+		dmg.Gbz80.Ime = false
+		dmg.Gbz80.Halted = false
+		dmg.BootROMMapped = true
+	} else {
+		dmg.SkipBoot()
+	}
+	dmg.ClearScreen()
+}
+
+// Step Execute a single instruction & render the screen
 func (dmg *DMG) Step() {
+	dmg.StepInstruction()
+	dmg.RenderFrame()
+}
+
+// RunFrame Execute instructions until the end of the current frame, or until a breakpoint fires (see BreakHit)
+func (dmg *DMG) RunFrame() {
+	frame := dmg.Frame
+	for dmg.Frame == frame {
+		dmg.StepInstruction()
+		if dmg.debugger != nil && dmg.debugger.hit != nil {
+			return
+		}
+	}
+}
+
+// StepInstruction Execute a single instruction & keep track of the elapsed time, the screen being only rendered at the end of frames
+func (dmg *DMG) StepInstruction() {
+	if dmg.debugger != nil && dmg.debugger.breakBeforeInstruction(dmg) {
+		return
+	}
 	dmg.ExecuteCurrentInstruction()
+	cycles := instructionCycles(dmg.LastOpcode)
+	dmg.Cycles += uint64(cycles)
+	dmg.frameCycles += cycles
+	if dmg.frameCycles >= CyclesPerFrame {
+		dmg.frameCycles -= CyclesPerFrame
+		dmg.endFrame()
+	}
+}
+
+// endFrame Called once per frame, when the LCD would enter VBlank
+func (dmg *DMG) endFrame() {
+	if dmg.cheats != nil {
+		dmg.applyGameShark()
+	}
+	dmg.Frame++
 	dmg.RenderFrame()
+	if dmg.movie != nil {
+		dmg.movieFrame()
+	}
+	if dmg.rewind != nil && dmg.Frame%uint64(dmg.rewind.interval) == 0 {
+		dmg.rewind.capture(dmg)
+	}
+}
+
+// trace Print executed instruction when tracing is enabled
+func (dmg *DMG) trace(format string, a ...any) {
+	if dmg.Trace {
+		fmt.Printf(format, a...)
+	}
 }
 
 func (d *DMG) RenderFrame() {
@@ -95,28 +222,20 @@ func (d *DMG) RenderFrame() {
 
 			// Temporary test
 			if (d.Gbz80.PC())%4 == 0 {
-				d.Screen[i] = color.RGBA{0x00, 0xBB, 0x00, 0xFF}
+				d.setPixel(i, PaletteBG, 2)
 			} else {
-				d.Screen[i] = color.RGBA{0xAA, 0xAA, 0xAA, 0xFF}
+				d.setPixel(i, PaletteBG, 1)
 			}
 		}
 	}
+	d.colorize()
 }
 
 func (d *DMG) ClearScreen() {
-	for i := range d.Screen {
-		d.Screen[i] = color.RGBA{0x00, 0x00, 0x00, 0xFF} // white
+	for i := range d.pixels {
+		d.setPixel(i, PaletteBG, 0) // white
 	}
-}
-
-func (d *DMG) Snapshot() image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
-	for y := 0; y < ScreenHeight; y++ {
-		for x := 0; x < ScreenWidth; x++ {
-			img.Set(x, y, d.Screen[y*ScreenWidth+x])
-		}
-	}
-	return img
+	d.colorize()
 }
 
 func (dmg *DMG) ExecuteCurrentInstruction() {
@@ -126,17 +245,22 @@ func (dmg *DMG) ExecuteCurrentInstruction() {
 
 	// First check for prefix & read opcode
 	isCBPrefixed := false
-	opcode := dmg.Memory[dmg.Gbz80.Pc]
+	opcode := dmg.GetMemoryU8(dmg.Gbz80.Pc)
 	var d uint8
-	print(d)
 	if opcode == 0xCB {
 		isCBPrefixed = true
-		d = dmg.Memory[dmg.Gbz80.Pc+1]
-		opcode = dmg.Memory[dmg.Gbz80.Pc+2]
+		d = dmg.GetMemoryU8(dmg.Gbz80.Pc + 1)
+		opcode = dmg.GetMemoryU8(dmg.Gbz80.Pc + 2)
 		dmg.Gbz80.Pc += 2
 	}
+	_ = d
 	dmg.Gbz80.Pc += 1
 
+	dmg.LastOpcode = uint16(opcode)
+	if isCBPrefixed {
+		dmg.LastOpcode |= 0xCB00
+	}
+
 	// Compute cpu matrix path values
 	x := opcode & 0b11000000 >> 6
 	y := opcode & 0b00111000 >> 3
@@ -156,17 +280,17 @@ func (dmg *DMG) ExecuteCurrentInstruction() {
 					NOP(dmg)
 				case 1:
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					dmg.Gbz80.Pc += 2
 					LDn16SP(dmg, nn)
 				case 2:
 					Stop(dmg)
 				case 3:
-					db := int8(dmg.Memory[dmg.Gbz80.Pc])
+					db := int8(dmg.GetMemoryU8(dmg.Gbz80.Pc))
 					dmg.Gbz80.Pc += 1
 					JRd(dmg, db)
 				default:
-					db := int8(dmg.Memory[dmg.Gbz80.Pc])
+					db := int8(dmg.GetMemoryU8(dmg.Gbz80.Pc))
 					cc := CC[y-4]
 					dmg.Gbz80.Pc += 1
 					JRCCd(dmg, cc, db)
@@ -175,7 +299,7 @@ func (dmg *DMG) ExecuteCurrentInstruction() {
 				// 16 bit load ops
 				if q == 0 {
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					LDr16n16(dmg, RP[p], nn)
 					dmg.Gbz80.Pc += 2
 				} else {
@@ -222,7 +346,7 @@ func (dmg *DMG) ExecuteCurrentInstruction() {
 			case 0x5:
 				DecR8(dmg, R[y])
 			case 0x6:
-				n := dmg.Memory[dmg.Gbz80.Pc]
+				n := dmg.GetMemoryU8(dmg.Gbz80.Pc)
 				LDr8n8(dmg, R[y], n)
 				dmg.Gbz80.Pc++
 			case 0x7:
@@ -278,46 +402,46 @@ func (dmg *DMG) ExecuteCurrentInstruction() {
 			case 0:
 				// Conditional return, mem-mapped register loads and stack operations
 				if y <= 3 {
-					fmt.Printf("RET %s", DIS_CC[y])
+					dmg.trace("RET %s", DIS_CC[y])
 					RetCc(dmg, CC[y])
 				} else if y == 4 {
-					n := dmg.Memory[dmg.Gbz80.Pc]
+					n := dmg.GetMemoryU8(dmg.Gbz80.Pc)
 					dmg.Gbz80.Pc += 1
-					fmt.Printf("LDH %d, A", n)
-					// TODO IMPLEMENT
+					dmg.trace("LDH %d, A", n)
+					LDHn16A(dmg, uint16(n))
 				} else if y == 5 {
-					db := int8(dmg.Memory[dmg.Gbz80.Pc])
+					db := int8(dmg.GetMemoryU8(dmg.Gbz80.Pc))
 					dmg.Gbz80.Pc += 1
-					fmt.Printf("ADD SP, %d", db)
+					dmg.trace("ADD SP, %d", db)
 					// TODO IMPLEMENT
 				} else if y == 6 {
-					n := dmg.Memory[dmg.Gbz80.Pc]
+					n := dmg.GetMemoryU8(dmg.Gbz80.Pc)
 					dmg.Gbz80.Pc += 1
-					fmt.Printf("LDH A, %d", n)
+					dmg.trace("LDH A, %d", n)
 					// TODO IMPLEMENT
 				} else if y == 7 {
-					db := int8(dmg.Memory[dmg.Gbz80.Pc])
+					db := int8(dmg.GetMemoryU8(dmg.Gbz80.Pc))
 					dmg.Gbz80.Pc += 1
-					fmt.Printf("LD HL, SP+ %d", db)
+					dmg.trace("LD HL, SP+ %d", db)
 					// TODO IMPLEMENT
 				}
 			case 1:
 				// POP & various ops
 				if q == 0 {
-					fmt.Printf("POP %s", DIS_RP2[p])
+					dmg.trace("POP %s", DIS_RP2[p])
 					Popr16(dmg, RP2[p])
 				} else {
 					if p == 0 {
-						fmt.Printf("RET")
+						dmg.trace("RET")
 						Ret(dmg)
 					} else if p == 1 {
-						fmt.Printf("RETI")
+						dmg.trace("RETI")
 						// TODO IMPLEMENT
 					} else if p == 2 {
-						fmt.Printf("JP HL")
+						dmg.trace("JP HL")
 						Jphl(dmg)
 					} else if p == 3 {
-						fmt.Printf("LD SP, HL")
+						dmg.trace("LD SP, HL")
 						// TODO IMPLEMENT
 					}
 				}
@@ -325,75 +449,75 @@ func (dmg *DMG) ExecuteCurrentInstruction() {
 				// Conditional jumps
 				if y <= 3 {
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					dmg.Gbz80.Pc += 2
-					fmt.Printf("JP %s, %d", DIS_CC[y], nn)
+					dmg.trace("JP %s, %d", DIS_CC[y], nn)
 					Jpccn16(dmg, CC[y], nn)
 				} else if y == 4 {
-					fmt.Printf("LDH C, A")
+					dmg.trace("LDH C, A")
 				} else if y == 5 {
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					dmg.Gbz80.Pc += 2
-					fmt.Printf("LD (%d), A", nn)
+					dmg.trace("LD (%d), A", nn)
 					// TODO: Implement
 				} else if y == 6 {
-					fmt.Printf("LDH A, C")
+					dmg.trace("LDH A, C")
 				} else if y == 7 {
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					dmg.Gbz80.Pc += 2
-					fmt.Printf("LD A, (%d)", nn)
+					dmg.trace("LD A, (%d)", nn)
 					// TODO: Implement
 				}
 			case 3:
 				if y == 0 {
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					dmg.Gbz80.Pc += 2
-					fmt.Printf("JP %d", nn)
+					dmg.trace("JP %d", nn)
 					Jpn16(dmg, nn)
 				} else if y == 6 {
-					fmt.Printf("DI")
+					dmg.trace("DI")
 					// TODO: Implement
 				} else if y == 7 {
-					fmt.Printf("EI")
+					dmg.trace("EI")
 					// TODO: Implement
 				} else {
-					fmt.Printf("WARNING : INVALID INSTRUCTIONS")
+					dmg.trace("WARNING : INVALID INSTRUCTIONS")
 				}
 			case 4:
 				if y <= 3 {
 					var nn uint16
-					nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+					nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 					dmg.Gbz80.Pc += 2
-					fmt.Printf("CALL %s, %d", DIS_CC[y], nn)
+					dmg.trace("CALL %s, %d", DIS_CC[y], nn)
 					// TODO: Implement
 				} else {
-					fmt.Printf("WARNING : INVALID INSTRUCTIONS")
+					dmg.trace("WARNING : INVALID INSTRUCTIONS")
 				}
 			case 5:
 				if q == 0 {
-					fmt.Printf("PUSH %s", DIS_RP2[p])
+					dmg.trace("PUSH %s", DIS_RP2[p])
 					Pushr16(dmg, RP2[p])
 				} else {
 					if p == 0 {
 						var nn uint16
-						nn = uint16(dmg.Memory[dmg.Gbz80.Pc])<<8 + uint16(dmg.Memory[dmg.Gbz80.Pc+1])
+						nn = uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc))<<8 + uint16(dmg.GetMemoryU8(dmg.Gbz80.Pc+1))
 						dmg.Gbz80.Pc += 2
-						fmt.Printf("CALL %d", nn)
+						dmg.trace("CALL %d", nn)
 						Calln16(dmg, nn)
 					} else {
-						fmt.Printf("WARNING : INVALID INSTRUCTIONS")
+						dmg.trace("WARNING : INVALID INSTRUCTIONS")
 					}
 				}
 			case 6:
-				n := dmg.Memory[dmg.Gbz80.Pc]
+				n := dmg.GetMemoryU8(dmg.Gbz80.Pc)
 				dmg.Gbz80.Pc += 1
-				fmt.Printf("%s %d", DIS_ALU[y], n)
+				dmg.trace("%s %d", DIS_ALU[y], n)
 				// TODO: Implement
 			case 7:
-				fmt.Printf("RST %d", y*8)
+				dmg.trace("RST %d", y*8)
 				// TODO: Implement
 			}
 		}
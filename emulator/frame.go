@@ -0,0 +1,71 @@
+package emulator
+
+import (
+	"image"
+)
+
+// Frames are double buffered : the PPU colors the back buffer, then swaps it with the front one when a frame is complete.
+// Frontends read the front buffer without copying or allocating anything, it stays untouched until the next frame completes.
+
+// frameBuffers Front & back buffers, with the signal sent when the front one changes
+type frameBuffers struct {
+	images [2]*image.RGBA
+	front  int
+	ready  chan struct{}
+}
+
+func newFrameBuffers() frameBuffers {
+	return frameBuffers{
+		images: [2]*image.RGBA{
+			image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight)),
+			image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight)),
+		},
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// back Buffer the next frame is drawn into
+func (f *frameBuffers) back() *image.RGBA {
+	return f.images[1-f.front]
+}
+
+// swap Show the back buffer, signaling the new frame without ever blocking the emulation
+func (f *frameBuffers) swap() {
+	f.front = 1 - f.front
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// FrameBuffer Get the last completed frame.
+// The image is owned by the DMG & reused : it is overwritten when the next frame completes, use Snapshot to keep a frame.
+func (dmg *DMG) FrameBuffer() *image.RGBA {
+	return dmg.frames.images[dmg.frames.front]
+}
+
+// FrameReady Receives a value when a new frame is available in FrameBuffer.
+// Frames completed while nobody is listening are coalesced into a single signal.
+func (dmg *DMG) FrameReady() <-chan struct{} {
+	return dmg.frames.ready
+}
+
+// Snapshot Get a copy of the last completed frame
+func (dmg *DMG) Snapshot() *image.RGBA {
+	front := dmg.FrameBuffer()
+	img := image.NewRGBA(front.Rect)
+	copy(img.Pix, front.Pix)
+	return img
+}
+
+// colorize Draw the pixels shades into the back buffer with the current color scheme, then show it
+func (dmg *DMG) colorize() {
+	palettes := [3]Palette{dmg.colors.BG, dmg.colors.OBJ0, dmg.colors.OBJ1}
+	pix := dmg.frames.back().Pix
+	for i, p := range dmg.pixels {
+		c := palettes[(p>>2)%3][p&0x03]
+		o := i * 4
+		pix[o], pix[o+1], pix[o+2], pix[o+3] = c.R, c.G, c.B, c.A
+	}
+	dmg.frames.swap()
+}
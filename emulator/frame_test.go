@@ -0,0 +1,59 @@
+package emulator
+
+import (
+	"testing"
+)
+
+// makeLoopDMG Create a DMG looping forever on JR -2
+func makeLoopDMG() *DMG {
+	dmg := MakeDMG()
+	dmg.Reset()
+	dmg.Memory[CartridgeHeaderEntryPoint] = 0x18
+	dmg.Memory[CartridgeHeaderEntryPoint+1] = 0xFE
+	return dmg
+}
+
+func TestFrameBuffer(t *testing.T) {
+	dmg := makeLoopDMG()
+	select {
+	case <-dmg.FrameReady():
+	default:
+		t.Error("clearing the screen should signal a frame")
+	}
+
+	first := dmg.FrameBuffer()
+	snapshot := dmg.Snapshot()
+	dmg.RunFrame()
+	dmg.RunFrame()
+	if dmg.FrameBuffer() != first {
+		t.Error("buffers should be swapped on every frame")
+	}
+	select {
+	case <-dmg.FrameReady():
+	default:
+		t.Error("expected a frame ready signal")
+	}
+	select {
+	case <-dmg.FrameReady():
+		t.Error("signals of frames nobody waited for should be coalesced")
+	default:
+	}
+
+	if snapshot == first || snapshot.RGBAAt(0, 0) != dmg.ColorScheme().BG[0] {
+		t.Error("snapshot should be a copy of the frame")
+	}
+
+	if allocs := testing.AllocsPerRun(10, dmg.RunFrame); allocs != 0 {
+		t.Errorf("expected no allocation per frame, got %v", allocs)
+	}
+}
+
+// BenchmarkRunFrame Headless frame throughput, rendering included
+func BenchmarkRunFrame(b *testing.B) {
+	dmg := makeLoopDMG()
+	b.ReportAllocs()
+	for b.Loop() {
+		dmg.RunFrame()
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "frames/s")
+}
@@ -6,11 +6,68 @@ import (
 
 // SetMemoryU8 sets Memory at address to value
 func (dmg *DMG) SetMemoryU8(address uint16, value uint8) {
+	if dmg.OnBusAccess != nil {
+		dmg.OnBusAccess(BusAccess{Address: address, Value: value, Write: true})
+	}
+	if dmg.debugger != nil {
+		dmg.debugger.watchAccess(BusAccess{Address: address, Value: value, Write: true})
+	}
+	if dmg.flatMemory {
+		dmg.Memory[address] = value
+		return
+	}
+	if address == BootROMDisableReg && value != 0 {
+		// Once unmapped, the boot ROM can't be mapped back until next power cycle
+		dmg.BootROMMapped = false
+	}
+	if isCGBRegister(address) && !dmg.CGBMode() {
+		// Not connected outside of CGB mode
+		return
+	}
+	if address == JoypadReg {
+		// Only the group selection bits are writable
+		before := dmg.joypadLines()
+		dmg.Memory[address] = value & 0x30
+		if before&^dmg.joypadLines() != 0 {
+			dmg.Memory[InterruptFlagReg] |= JoypadInterruptBit
+		}
+		return
+	}
+	if address == SerialControlReg {
+		dmg.writeSerialControl(value)
+		return
+	}
 	dmg.Memory[address] = value
 }
 
 // GetMemoryU8 gets Memory value at address
 func (dmg *DMG) GetMemoryU8(address uint16) uint8 {
+	value := dmg.readMemoryU8(address)
+	if dmg.OnBusAccess != nil {
+		dmg.OnBusAccess(BusAccess{Address: address, Value: value})
+	}
+	if dmg.debugger != nil {
+		dmg.debugger.watchAccess(BusAccess{Address: address, Value: value})
+	}
+	return value
+}
+
+func (dmg *DMG) readMemoryU8(address uint16) uint8 {
+	if dmg.flatMemory {
+		return dmg.Memory[address]
+	}
+	if dmg.isBootROMAddress(address) {
+		return dmg.BootROM[address]
+	}
+	if isCGBRegister(address) && !dmg.CGBMode() {
+		return 0xFF
+	}
+	if address == JoypadReg {
+		return dmg.readJoypad()
+	}
+	if address < 0x8000 && dmg.cheats != nil {
+		return dmg.cheats.patchROMRead(address, dmg.Memory[address])
+	}
 	return dmg.Memory[address]
 }
 
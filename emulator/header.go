@@ -0,0 +1,149 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Cartridge header, found at 0x100-0x14F of every ROM
+// See : https://gbdev.io/pandocs/The_Cartridge_Header.html
+
+// ErrROMTooSmall Returned when a ROM doesn't even hold a cartridge header
+var ErrROMTooSmall = errors.New("ROM too small to hold a cartridge header")
+
+// CartridgeHeader Parsed cartridge header
+type CartridgeHeader struct {
+	Title          string
+	Manufacturer   string // Manufacturer code, on newer cartridges only
+	CGBFlag        uint8
+	NewLicensee    string // Used when OldLicensee is 0x33
+	SGBFlag        uint8
+	CartridgeType  uint8
+	ROMSize        int // In bytes
+	RAMSize        int // In bytes
+	Japanese       bool
+	OldLicensee    uint8
+	Version        uint8
+	HeaderChecksum uint8
+	GlobalChecksum uint16
+}
+
+var cartridgeTypes = map[uint8]string{
+	0x00: "ROM ONLY",
+	0x01: "MBC1",
+	0x02: "MBC1+RAM",
+	0x03: "MBC1+RAM+BATTERY",
+	0x05: "MBC2",
+	0x06: "MBC2+BATTERY",
+	0x08: "ROM+RAM",
+	0x09: "ROM+RAM+BATTERY",
+	0x0B: "MMM01",
+	0x0C: "MMM01+RAM",
+	0x0D: "MMM01+RAM+BATTERY",
+	0x0F: "MBC3+TIMER+BATTERY",
+	0x10: "MBC3+TIMER+RAM+BATTERY",
+	0x11: "MBC3",
+	0x12: "MBC3+RAM",
+	0x13: "MBC3+RAM+BATTERY",
+	0x19: "MBC5",
+	0x1A: "MBC5+RAM",
+	0x1B: "MBC5+RAM+BATTERY",
+	0x1C: "MBC5+RUMBLE",
+	0x1D: "MBC5+RUMBLE+RAM",
+	0x1E: "MBC5+RUMBLE+RAM+BATTERY",
+	0x20: "MBC6",
+	0x22: "MBC7+SENSOR+RUMBLE+RAM+BATTERY",
+	0xFC: "POCKET CAMERA",
+	0xFD: "BANDAI TAMA5",
+	0xFE: "HuC3",
+	0xFF: "HuC1+RAM+BATTERY",
+}
+
+// Indexed by the RAM size code
+var cartridgeRAMSizes = []int{0, 0, 8 * 1024, 32 * 1024, 128 * 1024, 64 * 1024}
+
+// ParseHeader Parse the cartridge header of rom
+func ParseHeader(rom []uint8) (CartridgeHeader, error) {
+	if len(rom) <= CartridgeHeaderGlobalChecksum+1 {
+		return CartridgeHeader{}, ErrROMTooSmall
+	}
+
+	h := CartridgeHeader{
+		CGBFlag:        rom[CartridgeHeaderCGBFlag],
+		SGBFlag:        rom[CartridgeHeaderSGBFlag],
+		CartridgeType:  rom[CartridgeHeaderCartridgeType],
+		Japanese:       rom[CartridgeHeaderDestinationCode] == 0,
+		OldLicensee:    rom[CartridgeHeaderOldLicenseeCode],
+		Version:        rom[CartridgeHeaderMaskRomVersionNumber],
+		HeaderChecksum: rom[CartridgeHeaderHeaderChecksum],
+		GlobalChecksum: uint16(rom[CartridgeHeaderGlobalChecksum])<<8 | uint16(rom[CartridgeHeaderGlobalChecksum+1]),
+	}
+
+	// The title used to be 16 characters long, CGB cartridges use its end for the manufacturer code & CGB flag
+	titleEnd := CartridgeHeaderTitle + 16
+	if h.CGBFlag&0x80 != 0 {
+		titleEnd = CartridgeHeaderCGBFlag
+		if isHeaderText(rom[CartridgeHeaderManufacturerCode:CartridgeHeaderCGBFlag]) {
+			h.Manufacturer = string(rom[CartridgeHeaderManufacturerCode:CartridgeHeaderCGBFlag])
+			titleEnd = CartridgeHeaderManufacturerCode
+		}
+	}
+	h.Title = strings.TrimRight(string(rom[CartridgeHeaderTitle:titleEnd]), "\x00 ")
+
+	if h.OldLicensee == 0x33 {
+		h.NewLicensee = string(rom[CartridgeHeaderNewLicenseeCode : CartridgeHeaderNewLicenseeCode+2])
+	}
+	if code := rom[CartridgeHeaderRomSize]; code <= 8 {
+		h.ROMSize = 32 * 1024 << code
+	}
+	if code := int(rom[CartridgeHeaderRamSize]); code < len(cartridgeRAMSizes) {
+		h.RAMSize = cartridgeRAMSizes[code]
+	}
+
+	return h, nil
+}
+
+// isHeaderText Is text made of upper case letters & digits only
+func isHeaderText(text []uint8) bool {
+	for _, c := range text {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// CartridgeTypeName Name of the cartridge hardware (MBC1+RAM...)
+func (h CartridgeHeader) CartridgeTypeName() string {
+	if name, ok := cartridgeTypes[h.CartridgeType]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN (0x%02X)", h.CartridgeType)
+}
+
+// HeaderChecksumOK Check the header checksum against rom, as done by the boot ROM
+func HeaderChecksumOK(rom []uint8) bool {
+	if len(rom) <= CartridgeHeaderHeaderChecksum {
+		return false
+	}
+	var sum uint8
+	for _, b := range rom[CartridgeHeaderTitle:CartridgeHeaderHeaderChecksum] {
+		sum = sum - b - 1
+	}
+	return sum == rom[CartridgeHeaderHeaderChecksum]
+}
+
+// GlobalChecksumOK Check the global checksum (sum of every ROM byte but the checksum ones), not verified by the hardware
+func GlobalChecksumOK(rom []uint8) bool {
+	if len(rom) <= CartridgeHeaderGlobalChecksum+1 {
+		return false
+	}
+	var sum uint16
+	for i, b := range rom {
+		if i != CartridgeHeaderGlobalChecksum && i != CartridgeHeaderGlobalChecksum+1 {
+			sum += uint16(b)
+		}
+	}
+	return sum == uint16(rom[CartridgeHeaderGlobalChecksum])<<8|uint16(rom[CartridgeHeaderGlobalChecksum+1])
+}
@@ -0,0 +1,76 @@
+package emulator
+
+import (
+	"errors"
+	"testing"
+)
+
+// makeHeaderROM Build a 32KB ROM with a valid header
+func makeHeaderROM(title string, cgbFlag uint8) []uint8 {
+	rom := make([]uint8, 0x8000)
+	copy(rom[CartridgeHeaderTitle:], title)
+	rom[CartridgeHeaderCGBFlag] = cgbFlag
+	rom[CartridgeHeaderOldLicenseeCode] = 0x33
+	copy(rom[CartridgeHeaderNewLicenseeCode:], "01")
+	rom[CartridgeHeaderCartridgeType] = 0x03
+	rom[CartridgeHeaderRomSize] = 0x01
+	rom[CartridgeHeaderRamSize] = 0x02
+	rom[CartridgeHeaderDestinationCode] = 0x01
+
+	var sum uint8
+	for _, b := range rom[CartridgeHeaderTitle:CartridgeHeaderHeaderChecksum] {
+		sum = sum - b - 1
+	}
+	rom[CartridgeHeaderHeaderChecksum] = sum
+
+	var global uint16
+	for _, b := range rom {
+		global += uint16(b)
+	}
+	rom[CartridgeHeaderGlobalChecksum] = uint8(global >> 8)
+	rom[CartridgeHeaderGlobalChecksum+1] = uint8(global)
+	return rom
+}
+
+func TestParseHeader(t *testing.T) {
+	rom := makeHeaderROM("TETRIS", 0x00)
+	h, err := ParseHeader(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Title != "TETRIS" {
+		t.Errorf("title %q", h.Title)
+	}
+	if h.CartridgeTypeName() != "MBC1+RAM+BATTERY" {
+		t.Errorf("cartridge type %s", h.CartridgeTypeName())
+	}
+	if h.ROMSize != 64*1024 || h.RAMSize != 8*1024 {
+		t.Errorf("sizes %d/%d", h.ROMSize, h.RAMSize)
+	}
+	if h.NewLicensee != "01" || h.Japanese {
+		t.Errorf("licensee %q, japanese %v", h.NewLicensee, h.Japanese)
+	}
+	if !HeaderChecksumOK(rom) || !GlobalChecksumOK(rom) {
+		t.Error("checksums should be valid")
+	}
+	rom[CartridgeHeaderTitle] = 'X'
+	if HeaderChecksumOK(rom) {
+		t.Error("header checksum should be invalid")
+	}
+}
+
+func TestParseHeaderCGBTitle(t *testing.T) {
+	h, err := ParseHeader(makeHeaderROM("POKEMON_GLDAAUE", 0x80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Title != "POKEMON_GLD" || h.Manufacturer != "AAUE" {
+		t.Errorf("title %q, manufacturer %q", h.Title, h.Manufacturer)
+	}
+}
+
+func TestParseHeaderTooSmall(t *testing.T) {
+	if _, err := ParseHeader(make([]uint8, 0x100)); !errors.Is(err, ErrROMTooSmall) {
+		t.Errorf("expected ErrROMTooSmall, got %v", err)
+	}
+}
@@ -40,7 +40,7 @@ func LDr16A(dmg *DMG, r16 uint16) {
 
 // LDAr16 Copy the byte pointed to by r16 into register A.
 func LDAr16(dmg *DMG, r16 uint16) {
-	dmg.Gbz80.SetR8Register(R8_A, dmg.Memory[r16])
+	dmg.Gbz80.SetR8Register(R8_A, dmg.GetMemoryU8(r16))
 }
 
 // LDn16SP Copy SP & $FF at address n16 and SP >> 8 at address n16 + 1.
@@ -0,0 +1,124 @@
+package emulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Joypad, read by the game through the P1 register
+// See : https://gbdev.io/pandocs/Joypad_Input.html
+
+const (
+	JoypadReg          = 0xFF00 // P1
+	InterruptFlagReg   = 0xFF0F // IF
+	JoypadInterruptBit = 0x10
+)
+
+// Button Game Boy buttons, as a bit mask so that the whole joypad state fits in a byte
+type Button uint8
+
+const (
+	ButtonA Button = 1 << iota
+	ButtonB
+	ButtonSelect
+	ButtonStart
+	ButtonRight
+	ButtonLeft
+	ButtonUp
+	ButtonDown
+)
+
+// Buttons All the joypad buttons
+var Buttons = []Button{ButtonA, ButtonB, ButtonSelect, ButtonStart, ButtonRight, ButtonLeft, ButtonUp, ButtonDown}
+
+var buttonNames = map[Button]string{
+	ButtonA:      "A",
+	ButtonB:      "B",
+	ButtonSelect: "Select",
+	ButtonStart:  "Start",
+	ButtonRight:  "Right",
+	ButtonLeft:   "Left",
+	ButtonUp:     "Up",
+	ButtonDown:   "Down",
+}
+
+// String Name of a single button
+func (b Button) String() string {
+	if name, ok := buttonNames[b]; ok {
+		return name
+	}
+	return "?"
+}
+
+// ParseButtons Parse a set of buttons written as names joined by "+" (case insensitive, "A+Start"), "-" or "" meaning none
+func ParseButtons(text string) (Button, error) {
+	var buttons Button
+	if text == "" || text == "-" {
+		return 0, nil
+	}
+	for _, name := range strings.Split(text, "+") {
+		found := false
+		for button, n := range buttonNames {
+			if strings.EqualFold(n, strings.TrimSpace(name)) {
+				buttons |= button
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown button %q", name)
+		}
+	}
+	return buttons, nil
+}
+
+// SetJoypad Set the state of every button at once, buttons being the pressed ones.
+// Ignored while a movie is played back, the movie driving the joypad.
+func (dmg *DMG) SetJoypad(buttons Button) {
+	if dmg.MoviePlaying() {
+		return
+	}
+	dmg.setJoypad(buttons)
+}
+
+func (dmg *DMG) setJoypad(buttons Button) {
+	// Joypad interrupt is requested when a selected line goes low
+	before := dmg.joypadLines()
+	dmg.buttons = buttons
+	if before&^dmg.joypadLines() != 0 {
+		dmg.Memory[InterruptFlagReg] |= JoypadInterruptBit
+	}
+}
+
+// SetButton Press or release a single button
+func (dmg *DMG) SetButton(button Button, pressed bool) {
+	if pressed {
+		dmg.SetJoypad(dmg.buttons | button)
+	} else {
+		dmg.SetJoypad(dmg.buttons &^ button)
+	}
+}
+
+// Joypad Get the currently pressed buttons
+func (dmg *DMG) Joypad() Button {
+	return dmg.buttons
+}
+
+// joypadLines Input lines of P1 (bits 0-3), 0 meaning pressed on a selected group
+func (dmg *DMG) joypadLines() uint8 {
+	sel := dmg.Memory[JoypadReg]
+	lines := uint8(0x0F)
+	if sel&0x20 == 0 {
+		// Action buttons
+		lines &^= uint8(dmg.buttons) & 0x0F
+	}
+	if sel&0x10 == 0 {
+		// Direction buttons
+		lines &^= uint8(dmg.buttons) >> 4
+	}
+	return lines
+}
+
+// readJoypad Value of P1 as read by the CPU
+func (dmg *DMG) readJoypad() uint8 {
+	return 0xC0 | dmg.Memory[JoypadReg]&0x30 | dmg.joypadLines()
+}
@@ -0,0 +1,58 @@
+package emulator
+
+import "testing"
+
+func TestJoypadRead(t *testing.T) {
+	dmg := MakeDMG()
+	dmg.SetButton(ButtonA, true)
+	dmg.SetButton(ButtonDown, true)
+
+	// Select action buttons
+	dmg.SetMemoryU8(JoypadReg, 0x10)
+	if dmg.GetMemoryU8(JoypadReg) != 0xDE {
+		t.Errorf("expected P1 to be 0xDE with A pressed, got 0x%02X", dmg.GetMemoryU8(JoypadReg))
+	}
+
+	// Select direction buttons
+	dmg.SetMemoryU8(JoypadReg, 0x20)
+	if dmg.GetMemoryU8(JoypadReg) != 0xE7 {
+		t.Errorf("expected P1 to be 0xE7 with Down pressed, got 0x%02X", dmg.GetMemoryU8(JoypadReg))
+	}
+
+	// No group selected
+	dmg.SetMemoryU8(JoypadReg, 0x30)
+	if dmg.GetMemoryU8(JoypadReg) != 0xFF {
+		t.Errorf("expected P1 to be 0xFF with no group selected, got 0x%02X", dmg.GetMemoryU8(JoypadReg))
+	}
+}
+
+func TestJoypadInterrupt(t *testing.T) {
+	dmg := MakeDMG()
+	dmg.SetMemoryU8(JoypadReg, 0x10)
+
+	dmg.SetButton(ButtonUp, true)
+	if dmg.Memory[InterruptFlagReg]&JoypadInterruptBit != 0 {
+		t.Error("expected no interrupt for a button of an unselected group")
+	}
+
+	dmg.SetButton(ButtonStart, true)
+	if dmg.Memory[InterruptFlagReg]&JoypadInterruptBit == 0 {
+		t.Error("expected a joypad interrupt when Start is pressed")
+	}
+}
+
+func TestParseButtons(t *testing.T) {
+	buttons, err := ParseButtons("a+Start + down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buttons != ButtonA|ButtonStart|ButtonDown {
+		t.Errorf("unexpected buttons 0x%02X", buttons)
+	}
+	if buttons, _ := ParseButtons("-"); buttons != 0 {
+		t.Errorf("expected no button, got 0x%02X", buttons)
+	}
+	if _, err := ParseButtons("A+Turbo"); err == nil {
+		t.Error("expected an error for an unknown button")
+	}
+}
@@ -40,6 +40,7 @@ const (
 	CartridgeHeaderDestinationCode      = 0x14A
 	CartridgeHeaderOldLicenseeCode      = 0x14B
 	CartridgeHeaderMaskRomVersionNumber = 0x14C
+	CartridgeHeaderHeaderChecksum       = 0x14D
 	CartridgeHeaderGlobalChecksum       = 0x14E
 	ProgramStart                        = 0x150
 )
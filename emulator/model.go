@@ -0,0 +1,77 @@
+package emulator
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Model Game Boy hardware model being emulated
+type Model uint8
+
+const (
+	ModelDMG  Model = iota // Original Game Boy (DMG-CPU A/B/C)
+	ModelDMG0              // Early Japanese Game Boy (DMG-CPU 0)
+	ModelMGB               // Game Boy Pocket / Light
+	ModelSGB               // Super Game Boy
+	ModelSGB2              // Super Game Boy 2
+	ModelCGB               // Game Boy Color
+	ModelAGB               // Game Boy Advance (running GB/GBC software)
+)
+
+var modelNames = []string{"DMG", "DMG0", "MGB", "SGB", "SGB2", "CGB", "AGB"}
+
+// String Get the short name of the model (DMG, MGB, CGB...)
+func (m Model) String() string {
+	if int(m) < len(modelNames) {
+		return modelNames[m]
+	}
+	return "UNKNOWN"
+}
+
+// ParseModel Get the model matching name (case insensitive), as returned by Model.String
+func ParseModel(name string) (Model, error) {
+	for i, n := range modelNames {
+		if strings.EqualFold(n, name) {
+			return Model(i), nil
+		}
+	}
+	return ModelDMG, fmt.Errorf("unknown model %q (expected one of %s)", name, strings.Join(modelNames, ", "))
+}
+
+// IsCGB Is the model able to run Game Boy Color software
+func (m Model) IsCGB() bool {
+	return m == ModelCGB || m == ModelAGB
+}
+
+// IsSGB Is the model a Super Game Boy cartridge running on a SNES
+func (m Model) IsSGB() bool {
+	return m == ModelSGB || m == ModelSGB2
+}
+
+// ClockSpeed CPU clock in Hz.
+// The original SGB derives its clock from the SNES one, and runs about 2.4% faster than a handheld.
+func (m Model) ClockSpeed() int {
+	if m == ModelSGB {
+		return 4295454
+	}
+	return 4194304
+}
+
+// DefaultShades Colors of the 4 DMG shades (lightest to darkest) as displayed by the model screen
+func (m Model) DefaultShades() [4]color.RGBA {
+	switch m {
+	case ModelMGB:
+		// Pocket & Light have a black & white screen
+		return [4]color.RGBA{{0xE0, 0xDB, 0xCD, 0xFF}, {0xA8, 0x9F, 0x94, 0xFF}, {0x70, 0x6B, 0x66, 0xFF}, {0x2B, 0x2B, 0x26, 0xFF}}
+	case ModelSGB, ModelSGB2:
+		// Default SGB palette (1-A)
+		return [4]color.RGBA{{0xF8, 0xE8, 0xC8, 0xFF}, {0xD8, 0x90, 0x48, 0xFF}, {0xA8, 0x28, 0x20, 0xFF}, {0x30, 0x18, 0x50, 0xFF}}
+	case ModelCGB, ModelAGB:
+		// Palette picked by the CGB boot ROM for unknown DMG games
+		return [4]color.RGBA{{0xFF, 0xFF, 0xFF, 0xFF}, {0x7B, 0xFF, 0x31, 0xFF}, {0x00, 0x63, 0xC5, 0xFF}, {0x00, 0x00, 0x00, 0xFF}}
+	default:
+		// Green tinted DMG screen
+		return [4]color.RGBA{{0x9B, 0xBC, 0x0F, 0xFF}, {0x8B, 0xAC, 0x0F, 0xFF}, {0x30, 0x62, 0x30, 0xFF}, {0x0F, 0x38, 0x0F, 0xFF}}
+	}
+}
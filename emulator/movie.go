@@ -0,0 +1,318 @@
+package emulator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Movies record the joypad state of every frame, so that a run can be replayed exactly.
+// A movie starts from power on, or from a save state embedded in it.
+// The machine state checksum is recorded at the end of each frame, so that replays diverging from the recording are detected.
+//
+// Movie file format :
+//
+//	+--------+---------------------------------------------------------------------+
+//	| Size   | Content                                                             |
+//	+--------+---------------------------------------------------------------------+
+//	| 8      | Magic "GOGBMOVI"                                                    |
+//	| 2      | Format version                                                      |
+//	| 1      | Model                                                               |
+//	| 20     | SHA-1 of the ROM                                                    |
+//	| 2 + n  | Version of the emulator that recorded the movie                     |
+//	| 4 + n  | Save state the movie starts from, empty when starting from power on |
+//	| 4      | Frame count                                                         |
+//	| 5 * n  | Frames : pressed buttons (1 byte) & state checksum (4 bytes)        |
+//	+--------+---------------------------------------------------------------------+
+//
+// All values are little endian, sizes of variable length fields are given before them.
+
+const (
+	MovieMagic   = "GOGBMOVI"
+	MovieVersion = 1
+	// Version Emulator version, recorded in movies
+	Version = "0.1.0-dev"
+)
+
+// ErrMovieROMMismatch Returned when playing a movie recorded with a different ROM
+var ErrMovieROMMismatch = errors.New("movie was recorded with a different ROM")
+
+// DesyncError Returned when a movie played back diverges from its recording
+type DesyncError struct {
+	Frame    int // Movie frame at the end of which states differ
+	Expected uint32
+	Got      uint32
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("desync at movie frame %d: state checksum 0x%08X, recorded 0x%08X", e.Frame, e.Got, e.Expected)
+}
+
+// MovieFrame Input & outcome of a frame
+type MovieFrame struct {
+	Buttons  Button // Pressed during the frame
+	Checksum uint32 // StateChecksum at the end of the frame
+}
+
+// Movie Recorded run
+type Movie struct {
+	Version string // Emulator version the movie was recorded with
+	Model   Model
+	ROMHash [sha1.Size]byte
+	State   []byte // Save state the movie starts from, empty when starting from power on
+	Frames  []MovieFrame
+	// NoChecksums Set for imported movies, until checksums are filled in by playing them back to their end
+	NoChecksums bool
+}
+
+type movieHeader struct {
+	Magic   [8]byte
+	Version uint16
+	Model   Model
+	ROMHash [sha1.Size]byte
+}
+
+// movieSession Movie being recorded or played back
+type movieSession struct {
+	movie      *Movie
+	recording  bool
+	verify     bool   // Check state checksums when playing back
+	startFrame uint64 // DMG frame the movie started on
+	frame      int    // Movie frame being played back
+}
+
+// Write Write the movie to w
+func (m *Movie) Write(w io.Writer) error {
+	if m.NoChecksums {
+		return errors.New("movie has no checksums yet, play it back to its end first")
+	}
+	var buf bytes.Buffer
+	header := movieHeader{Version: MovieVersion, Model: m.Model, ROMHash: m.ROMHash}
+	copy(header.Magic[:], MovieMagic)
+	_ = binary.Write(&buf, binary.LittleEndian, header)
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(m.Version)))
+	buf.WriteString(m.Version)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(m.State)))
+	buf.Write(m.State)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(m.Frames)))
+	for _, f := range m.Frames {
+		buf.WriteByte(uint8(f.Buttons))
+		_ = binary.Write(&buf, binary.LittleEndian, f.Checksum)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// maxMovieStateSize Largest state a movie can start from, well above the size of a save state
+const maxMovieStateSize = 1 << 20
+
+// ReadMovie Read a movie written by Movie.Write
+func ReadMovie(r io.Reader) (*Movie, error) {
+	var header movieHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("invalid movie header: %w", err)
+	}
+	if string(header.Magic[:]) != MovieMagic {
+		return nil, errors.New("not a movie file")
+	}
+	if header.Version == 0 || header.Version > MovieVersion {
+		return nil, fmt.Errorf("unsupported movie version %d", header.Version)
+	}
+	m := &Movie{Model: header.Model, ROMHash: header.ROMHash}
+
+	var versionLength uint16
+	if err := binary.Read(r, binary.LittleEndian, &versionLength); err != nil {
+		return nil, fmt.Errorf("invalid movie header: %w", err)
+	}
+	version := make([]byte, versionLength)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return nil, fmt.Errorf("invalid movie header: %w", err)
+	}
+	m.Version = string(version)
+
+	var stateLength uint32
+	if err := binary.Read(r, binary.LittleEndian, &stateLength); err != nil {
+		return nil, fmt.Errorf("invalid movie state: %w", err)
+	}
+	if stateLength > maxMovieStateSize {
+		return nil, fmt.Errorf("invalid movie state: %d bytes, at most %d expected", stateLength, maxMovieStateSize)
+	}
+	if stateLength > 0 {
+		var state bytes.Buffer
+		if _, err := io.CopyN(&state, r, int64(stateLength)); err != nil {
+			return nil, fmt.Errorf("invalid movie state: %w", err)
+		}
+		m.State = state.Bytes()
+	}
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("invalid movie frames: %w", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(count)*5 {
+		return nil, fmt.Errorf("invalid movie frames: expected %d frames, got %d bytes", count, len(data))
+	}
+	m.Frames = make([]MovieFrame, count)
+	for i := range m.Frames {
+		m.Frames[i] = MovieFrame{
+			Buttons:  Button(data[i*5]),
+			Checksum: binary.LittleEndian.Uint32(data[i*5+1:]),
+		}
+	}
+	return m, nil
+}
+
+// ROMHash SHA-1 of the loaded ROM, identifying it in movies
+func (dmg *DMG) ROMHash() [sha1.Size]byte {
+	return sha1.Sum(dmg.ROM)
+}
+
+// StateChecksum CRC32 of the CPU registers & memory, changing whenever emulation diverges
+func (dmg *DMG) StateChecksum() uint32 {
+	var regs [13]byte
+	binary.LittleEndian.PutUint16(regs[0:], dmg.Gbz80.Af)
+	binary.LittleEndian.PutUint16(regs[2:], dmg.Gbz80.Bc)
+	binary.LittleEndian.PutUint16(regs[4:], dmg.Gbz80.De)
+	binary.LittleEndian.PutUint16(regs[6:], dmg.Gbz80.Hl)
+	binary.LittleEndian.PutUint16(regs[8:], dmg.Gbz80.Sp)
+	binary.LittleEndian.PutUint16(regs[10:], dmg.Gbz80.Pc)
+	regs[12] = boolToU8(dmg.Gbz80.Ime) | boolToU8(dmg.Gbz80.Halted)<<1
+	crc := crc32.ChecksumIEEE(regs[:])
+	return crc32.Update(crc, crc32.IEEETable, dmg.Memory[:])
+}
+
+// RecordMovie Start recording a movie : from power on, the console being reset, or from the current state when fromState is set.
+// Rewinding while recording drops the frames rewound.
+func (dmg *DMG) RecordMovie(fromState bool) error {
+	m := &Movie{Version: Version, Model: dmg.Model, ROMHash: dmg.ROMHash()}
+	if fromState {
+		var state bytes.Buffer
+		if err := dmg.SaveState(&state); err != nil {
+			return err
+		}
+		m.State = state.Bytes()
+	} else {
+		dmg.Reset()
+	}
+	dmg.movie = &movieSession{movie: m, recording: true, startFrame: dmg.Frame}
+	dmg.movieErr = nil
+	return nil
+}
+
+// PlayMovie Play m back from its start : the movie drives the joypad until its end, SetJoypad being ignored meanwhile.
+// When verify is set, playback stops with a DesyncError as soon as the state differs from the recording.
+// Imported movies are never verified : their checksums are filled in instead.
+func (dmg *DMG) PlayMovie(m *Movie, verify bool) error {
+	if m.ROMHash != dmg.ROMHash() {
+		return ErrMovieROMMismatch
+	}
+	if m.Model != dmg.Model {
+		return fmt.Errorf("movie was recorded on a %s, emulating a %s", m.Model, dmg.Model)
+	}
+	if len(m.State) > 0 {
+		if err := dmg.LoadState(bytes.NewReader(m.State)); err != nil {
+			return fmt.Errorf("invalid movie state: %w", err)
+		}
+	} else {
+		dmg.Reset()
+	}
+	dmg.movie = nil
+	dmg.movieErr = nil
+	if len(m.Frames) == 0 {
+		return nil
+	}
+	dmg.movie = &movieSession{movie: m, verify: verify, startFrame: dmg.Frame}
+	dmg.buttons = m.Frames[0].Buttons
+	return nil
+}
+
+// StopMovie Stop recording or playing back, returning the movie recorded if any
+func (dmg *DMG) StopMovie() *Movie {
+	s := dmg.movie
+	dmg.movie = nil
+	if s == nil || !s.recording {
+		return nil
+	}
+	return s.movie
+}
+
+// MovieRecording Is a movie being recorded
+func (dmg *DMG) MovieRecording() bool {
+	return dmg.movie != nil && dmg.movie.recording
+}
+
+// MoviePlaying Is a movie being played back
+func (dmg *DMG) MoviePlaying() bool {
+	return dmg.movie != nil && !dmg.movie.recording
+}
+
+// MovieError Get the error that ended the last playback, nil when it ran to its end
+func (dmg *DMG) MovieError() error {
+	return dmg.movieErr
+}
+
+// movieFrame Called at the end of each frame : record it, or check it & apply the input of the next one
+func (dmg *DMG) movieFrame() {
+	s := dmg.movie
+	if s.recording {
+		s.movie.Frames = append(s.movie.Frames, MovieFrame{Buttons: dmg.buttons, Checksum: dmg.StateChecksum()})
+		return
+	}
+
+	frames := s.movie.Frames
+	if s.movie.NoChecksums {
+		frames[s.frame].Checksum = dmg.StateChecksum()
+		if s.frame == len(frames)-1 {
+			s.movie.NoChecksums = false
+		}
+	} else if s.verify {
+		if got := dmg.StateChecksum(); got != frames[s.frame].Checksum {
+			dmg.endMovie(&DesyncError{Frame: s.frame, Expected: frames[s.frame].Checksum, Got: got})
+			return
+		}
+	}
+	s.frame++
+	if s.frame == len(frames) {
+		dmg.endMovie(nil)
+		return
+	}
+	dmg.setJoypad(frames[s.frame].Buttons)
+}
+
+// endMovie Stop playing back, err being the reason it ended early
+func (dmg *DMG) endMovie(err error) {
+	dmg.movie = nil
+	dmg.movieErr = err
+	if dmg.OnMovieEnd != nil {
+		dmg.OnMovieEnd(err)
+	}
+}
+
+// rewindMovie Follow the DMG back in time : drop the recorded frames that were rewound, or go back in the movie played
+func (dmg *DMG) rewindMovie() {
+	s := dmg.movie
+	if s == nil {
+		return
+	}
+	frame := 0
+	if dmg.Frame > s.startFrame {
+		frame = int(dmg.Frame - s.startFrame)
+	}
+	frames := s.movie.Frames
+	switch {
+	case frame >= len(frames):
+	case s.recording:
+		s.movie.Frames = frames[:frame]
+	default:
+		s.frame = frame
+		dmg.buttons = frames[frame].Buttons
+	}
+}
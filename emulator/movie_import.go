@@ -0,0 +1,230 @@
+package emulator
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Movies recorded by other emulators are imported as per-frame joypad input.
+// They have no state checksums : these are computed when the movie is first played back, so that the converted movie,
+// once saved, can be replayed as a regression test of emulation determinism.
+//
+// VBM (VisualBoyAdvance) header, all values little endian :
+//
+//	+--------+---------------------------------------------------------------+
+//	| Offset | Content                                                       |
+//	+--------+---------------------------------------------------------------+
+//	| 0x00   | Magic "VBM\x1A"                                               |
+//	| 0x0C   | Frame count (4 bytes)                                         |
+//	| 0x14   | Start flags : bit 0 from a save state, bit 1 from SRAM        |
+//	| 0x15   | Controller flags : bits 0-3 controllers 1-4 in use            |
+//	| 0x16   | System flags : bit 0 GBA, bit 1 GBC, bit 2 SGB                |
+//	| 0x24   | ROM title (12 bytes)                                          |
+//	| 0x31   | ROM header checksum                                           |
+//	| 0x3C   | Offset of the controller data (4 bytes)                       |
+//	+--------+---------------------------------------------------------------+
+//
+// Controller data holds 2 bytes per frame & controller in use, the low byte using the same bits as Button.
+//
+// BK2 (BizHawk) movies are zip archives : "Header.txt" holds "Key Value" lines (Platform, SHA1, StartsFromSavestate...),
+// "Input Log.txt" holds a "LogKey:#Up|Down|..." line naming the buttons, then a "|UDLRsSBA.|" line per frame,
+// each button being shown by its mnemonic when pressed and by a dot otherwise.
+
+const (
+	VBMMagic          = "VBM\x1A"
+	vbmHeaderSize     = 0x40
+	bk2HeaderFile     = "Header.txt"
+	bk2InputLogFile   = "Input Log.txt"
+	vbmStartFromState = 0x01
+	vbmStartFromSRAM  = 0x02
+	vbmSystemGBA      = 0x01
+	vbmSystemGBC      = 0x02
+	vbmSystemSGB      = 0x04
+)
+
+// ErrUnsupportedMovie Returned when importing a movie relying on features that can't be replayed here
+var ErrUnsupportedMovie = errors.New("unsupported movie")
+
+// bk2Buttons Buttons of BizHawk input logs, by name
+var bk2Buttons = map[string]Button{
+	"Up":     ButtonUp,
+	"Down":   ButtonDown,
+	"Left":   ButtonLeft,
+	"Right":  ButtonRight,
+	"Start":  ButtonStart,
+	"Select": ButtonSelect,
+	"B":      ButtonB,
+	"A":      ButtonA,
+}
+
+// ReadVBM Import a VisualBoyAdvance movie recorded with rom
+func ReadVBM(r io.Reader, rom []uint8) (*Movie, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < vbmHeaderSize || string(data[:4]) != VBMMagic {
+		return nil, errors.New("not a VBM movie")
+	}
+
+	start, controllers, system := data[0x14], data[0x15], data[0x16]
+	switch {
+	case start&vbmStartFromState != 0:
+		return nil, fmt.Errorf("%w: VBM movies starting from a save state can't be imported", ErrUnsupportedMovie)
+	case start&vbmStartFromSRAM != 0:
+		return nil, fmt.Errorf("%w: VBM movies starting from SRAM can't be imported", ErrUnsupportedMovie)
+	case system&vbmSystemGBA != 0:
+		return nil, fmt.Errorf("%w: GBA movie", ErrUnsupportedMovie)
+	case controllers&0x0F == 0:
+		return nil, errors.New("invalid VBM movie: no controller in use")
+	}
+
+	if len(rom) <= CartridgeHeaderHeaderChecksum ||
+		!bytes.Equal(data[0x24:0x30], rom[CartridgeHeaderTitle:CartridgeHeaderTitle+12]) ||
+		data[0x31] != rom[CartridgeHeaderHeaderChecksum] {
+		return nil, ErrMovieROMMismatch
+	}
+
+	m := &Movie{Version: "VBM", Model: ModelDMG, ROMHash: sha1.Sum(rom), NoChecksums: true}
+	switch {
+	case system&vbmSystemGBC != 0:
+		m.Model = ModelCGB
+	case system&vbmSystemSGB != 0:
+		m.Model = ModelSGB
+	}
+
+	// Only the first controller in use drives the Game Boy
+	stride := 0
+	for i := 0; i < 4; i++ {
+		if controllers&(1<<i) != 0 {
+			stride += 2
+		}
+	}
+	count := int(binary.LittleEndian.Uint32(data[0x0C:]))
+	offset := int(binary.LittleEndian.Uint32(data[0x3C:]))
+	if offset < vbmHeaderSize || offset+count*stride > len(data) {
+		return nil, fmt.Errorf("invalid VBM movie: %d frames don't fit in the file", count)
+	}
+	m.Frames = make([]MovieFrame, count)
+	for i := range m.Frames {
+		m.Frames[i].Buttons = Button(data[offset+i*stride])
+	}
+	return m, nil
+}
+
+// ReadBK2 Import a BizHawk movie recorded with rom, r being the zip archive of size bytes
+func ReadBK2(r io.ReaderAt, size int64, rom []uint8) (*Movie, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a BK2 movie: %w", err)
+	}
+	header, err := readBK2Header(archive)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(header["StartsFromSavestate"], "True") || strings.EqualFold(header["StartsFromSaveRam"], "True") {
+		return nil, fmt.Errorf("%w: BK2 movies starting from a save state or SRAM can't be imported", ErrUnsupportedMovie)
+	}
+
+	m := &Movie{Version: header["MovieVersion"], ROMHash: sha1.Sum(rom), NoChecksums: true}
+	if hash := header["SHA1"]; hash != "" {
+		recorded, err := hex.DecodeString(hash)
+		if err != nil || !bytes.Equal(recorded, m.ROMHash[:]) {
+			return nil, ErrMovieROMMismatch
+		}
+	}
+	switch strings.ToUpper(header["Platform"]) {
+	case "GB", "":
+		m.Model = ModelDMG
+		if header["IsCGBMode"] == "1" {
+			m.Model = ModelCGB
+		}
+	case "GBC":
+		m.Model = ModelCGB
+	case "SGB":
+		m.Model = ModelSGB
+	default:
+		return nil, fmt.Errorf("%w: %s movie", ErrUnsupportedMovie, header["Platform"])
+	}
+
+	f, err := archive.Open(bk2InputLogFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BK2 movie: %w", err)
+	}
+	defer f.Close()
+	m.Frames, err = readBK2InputLog(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BK2 movie: %w", err)
+	}
+	return m, nil
+}
+
+// readBK2Header Read the "Key Value" lines of the BK2 header
+func readBK2Header(archive *zip.Reader) (map[string]string, error) {
+	f, err := archive.Open(bk2HeaderFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BK2 movie: %w", err)
+	}
+	defer f.Close()
+	header := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		header[key] = strings.TrimSpace(value)
+	}
+	return header, scanner.Err()
+}
+
+// readBK2InputLog Read the buttons of every frame from a BK2 input log
+func readBK2InputLog(r io.Reader) ([]MovieFrame, error) {
+	var keys []Button // Button of each column, 0 for the ones that aren't joypad buttons (Power...)
+	var frames []MovieFrame
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(text, "LogKey:"):
+			keys = nil
+			for _, group := range strings.Split(strings.TrimPrefix(text, "LogKey:"), "#") {
+				for _, name := range strings.Split(group, "|") {
+					if name == "" {
+						continue
+					}
+					keys = append(keys, bk2Buttons[strings.TrimPrefix(name, "P1 ")])
+				}
+			}
+		case strings.HasPrefix(text, "|"):
+			if keys == nil {
+				return nil, fmt.Errorf("line %d: input before the LogKey line", line)
+			}
+			columns := strings.ReplaceAll(text, "|", "")
+			if len(columns) != len(keys) {
+				return nil, fmt.Errorf("line %d: expected %d buttons, got %q", line, len(keys), text)
+			}
+			var frame MovieFrame
+			for i := range len(columns) {
+				if columns[i] != '.' && columns[i] != ' ' {
+					frame.Buttons |= keys[i]
+				}
+			}
+			frames = append(frames, frame)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		return nil, errors.New("missing LogKey line")
+	}
+	return frames, nil
+}
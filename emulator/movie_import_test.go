@@ -0,0 +1,122 @@
+package emulator
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// makeVBM Build a VBM movie for rom, with 2 controllers in use
+func makeVBM(rom []uint8, frames ...Button) []byte {
+	data := make([]byte, vbmHeaderSize)
+	copy(data, VBMMagic)
+	binary.LittleEndian.PutUint32(data[0x04:], 1)
+	binary.LittleEndian.PutUint32(data[0x0C:], uint32(len(frames)))
+	data[0x15] = 0x03
+	copy(data[0x24:0x30], rom[CartridgeHeaderTitle:])
+	data[0x31] = rom[CartridgeHeaderHeaderChecksum]
+	binary.LittleEndian.PutUint32(data[0x3C:], vbmHeaderSize)
+	for _, b := range frames {
+		data = append(data, uint8(b), 0x00, 0xFF, 0xFF)
+	}
+	return data
+}
+
+func TestReadVBM(t *testing.T) {
+	dmg := makeProgramDMG(t, joypadProgram...)
+	copy(dmg.ROM[CartridgeHeaderTitle:], "TETRIS")
+	data := makeVBM(dmg.ROM, 0, ButtonA|ButtonRight, ButtonStart)
+	m, err := ReadVBM(bytes.NewReader(data), dmg.ROM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.NoChecksums || m.Model != ModelDMG || len(m.Frames) != 3 || m.Frames[1].Buttons != ButtonA|ButtonRight || m.Frames[2].Buttons != ButtonStart {
+		t.Fatalf("unexpected movie %+v", m)
+	}
+
+	other := bytes.Clone(dmg.ROM)
+	copy(other[CartridgeHeaderTitle:], "ZELDA")
+	if _, err := ReadVBM(bytes.NewReader(data), other); !errors.Is(err, ErrMovieROMMismatch) {
+		t.Errorf("expected a ROM mismatch, got %v", err)
+	}
+	data[0x14] = vbmStartFromState
+	if _, err := ReadVBM(bytes.NewReader(data), dmg.ROM); !errors.Is(err, ErrUnsupportedMovie) {
+		t.Errorf("expected movies starting from a state to be rejected, got %v", err)
+	}
+	if _, err := ReadVBM(bytes.NewReader(data[:0x20]), dmg.ROM); err == nil {
+		t.Error("expected an error for a truncated movie")
+	}
+}
+
+// makeBK2 Build a BK2 archive holding header & input log
+func makeBK2(t *testing.T, header, log string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{bk2HeaderFile: header, bk2InputLogFile: log} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestReadBK2AndFillChecksums(t *testing.T) {
+	dmg := makeProgramDMG(t, joypadProgram...)
+	hash := sha1.Sum(dmg.ROM)
+	header := "MovieVersion BizHawk v2.0.0\nPlatform GB\nSHA1 " + hex.EncodeToString(hash[:]) + "\n"
+	log := "[Input]\nLogKey:#Up|Down|Left|Right|Start|Select|B|A|Power|\n" +
+		"|........P|\n" +
+		"|.......A.|\n" +
+		"|U...S....|\n" +
+		"[/Input]\n"
+	r := makeBK2(t, header, log)
+	m, err := ReadBK2(r, r.Size(), dmg.ROM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Frames) != 3 || m.Frames[0].Buttons != 0 || m.Frames[1].Buttons != ButtonA || m.Frames[2].Buttons != ButtonUp|ButtonStart {
+		t.Fatalf("unexpected frames %+v", m.Frames)
+	}
+	if err := m.Write(&bytes.Buffer{}); err == nil {
+		t.Error("movies without checksums shouldn't be written")
+	}
+
+	// Playing the movie back fills its checksums in, the converted movie then replays without desync
+	if err := dmg.PlayMovie(m, true); err != nil {
+		t.Fatal(err)
+	}
+	for range m.Frames {
+		dmg.RunFrame()
+	}
+	if m.NoChecksums || dmg.MovieError() != nil {
+		t.Fatalf("expected checksums to be filled, got error %v", dmg.MovieError())
+	}
+	if err := dmg.PlayMovie(m, true); err != nil {
+		t.Fatal(err)
+	}
+	for range m.Frames {
+		dmg.RunFrame()
+	}
+	if dmg.MovieError() != nil {
+		t.Errorf("unexpected desync %v", dmg.MovieError())
+	}
+
+	r = makeBK2(t, "Platform GB\nSHA1 0000\n", log)
+	if _, err := ReadBK2(r, r.Size(), dmg.ROM); !errors.Is(err, ErrMovieROMMismatch) {
+		t.Errorf("expected a ROM mismatch, got %v", err)
+	}
+	r = makeBK2(t, "Platform GB\n", "|....|\n")
+	if _, err := ReadBK2(r, r.Size(), dmg.ROM); err == nil {
+		t.Error("expected an error for input without LogKey")
+	}
+}
@@ -0,0 +1,157 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// makeProgramDMG Create a DMG with a ROM running program from the entry point
+func makeProgramDMG(t *testing.T, program ...uint8) *DMG {
+	t.Helper()
+	rom := make([]uint8, 0x8000)
+	copy(rom[CartridgeHeaderEntryPoint:], program)
+	dmg := MakeDMG()
+	if err := dmg.LoadROMFile(ROMFile{Path: "test.gb", Name: "test.gb", Data: rom}); err != nil {
+		t.Fatal(err)
+	}
+	dmg.Reset()
+	return dmg
+}
+
+// joypadProgram Select the action buttons then loop, so that pressing one requests the joypad interrupt
+var joypadProgram = []uint8{
+	0x3E, 0x10, // LD A, 0x10
+	0xE0, 0x00, // LDH (P1), A
+	0x18, 0xFE, // JR -2
+}
+
+func recordTestMovie(t *testing.T, dmg *DMG) *Movie {
+	t.Helper()
+	if err := dmg.RecordMovie(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, buttons := range []Button{0, ButtonA, ButtonA, ButtonStart, 0} {
+		dmg.SetJoypad(buttons)
+		dmg.RunFrame()
+	}
+	m := dmg.StopMovie()
+	if m == nil || len(m.Frames) != 5 {
+		t.Fatalf("unexpected recording %+v", m)
+	}
+	return m
+}
+
+func TestMovieRecordAndPlay(t *testing.T) {
+	dmg := makeProgramDMG(t, joypadProgram...)
+	recorded := recordTestMovie(t, dmg)
+	if recorded.Frames[1].Buttons != ButtonA || recorded.Frames[1].Checksum == recorded.Frames[0].Checksum {
+		t.Errorf("unexpected frames %+v", recorded.Frames)
+	}
+
+	var buf bytes.Buffer
+	if err := recorded.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadMovie(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != Version || m.ROMHash != dmg.ROMHash() || len(m.Frames) != len(recorded.Frames) || m.Frames[3] != recorded.Frames[3] {
+		t.Fatalf("movie changed when written & read back: %+v", m)
+	}
+
+	var ended []error
+	dmg.OnMovieEnd = func(err error) {
+		ended = append(ended, err)
+	}
+	if err := dmg.PlayMovie(m, true); err != nil {
+		t.Fatal(err)
+	}
+	for i := range m.Frames {
+		if dmg.Joypad() != m.Frames[i].Buttons {
+			t.Errorf("frame %d: expected buttons 0x%02X, got 0x%02X", i, m.Frames[i].Buttons, dmg.Joypad())
+		}
+		dmg.SetJoypad(ButtonB) // Ignored
+		dmg.RunFrame()
+	}
+	if dmg.MoviePlaying() || len(ended) != 1 || ended[0] != nil {
+		t.Errorf("movie should have ended without error, got %v", ended)
+	}
+}
+
+func TestMovieDesync(t *testing.T) {
+	dmg := makeProgramDMG(t, joypadProgram...)
+	m := recordTestMovie(t, dmg)
+	m.Frames[1].Buttons = 0
+	if err := dmg.PlayMovie(m, true); err != nil {
+		t.Fatal(err)
+	}
+	for range m.Frames {
+		dmg.RunFrame()
+	}
+	var desync *DesyncError
+	if !errors.As(dmg.MovieError(), &desync) || desync.Frame != 1 {
+		t.Errorf("expected a desync on frame 1, got %v", dmg.MovieError())
+	}
+
+	other := makeProgramDMG(t, 0x18, 0xFE)
+	if err := other.PlayMovie(m, true); !errors.Is(err, ErrMovieROMMismatch) {
+		t.Errorf("expected a ROM mismatch, got %v", err)
+	}
+}
+
+func TestMovieFromStateAndRewind(t *testing.T) {
+	dmg := makeProgramDMG(t, joypadProgram...)
+	dmg.EnableRewind(RewindOptions{Interval: 1, Budget: DefaultRewindBudget})
+	dmg.RunFrame()
+	if err := dmg.RecordMovie(true); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		dmg.RunFrame()
+	}
+	if err := dmg.Rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	dmg.SetJoypad(ButtonSelect)
+	dmg.RunFrame()
+	m := dmg.StopMovie()
+	if len(m.State) == 0 || len(m.Frames) != 4 || m.Frames[3].Buttons != ButtonSelect {
+		t.Fatalf("expected rewound frames to be dropped, got %+v", m.Frames)
+	}
+	var movie bytes.Buffer
+	if err := m.Write(&movie); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := ReadMovie(bytes.NewReader(movie.Bytes())); err != nil || !bytes.Equal(read.State, m.State) {
+		t.Fatalf("expected the state to be read back, got %v", err)
+	}
+	// The state length follows the header & the emulator version
+	oversized := bytes.Clone(movie.Bytes())
+	offset := binary.Size(movieHeader{}) + 2 + len(m.Version)
+	binary.LittleEndian.PutUint32(oversized[offset:], 0xFFFFFFFF)
+	if _, err := ReadMovie(bytes.NewReader(oversized)); err == nil || !strings.Contains(err.Error(), "invalid movie state") {
+		t.Errorf("expected an oversized state to be rejected, got %v", err)
+	}
+	versionZero := bytes.Clone(movie.Bytes())
+	binary.LittleEndian.PutUint16(versionZero[len(MovieMagic):], 0)
+	if _, err := ReadMovie(bytes.NewReader(versionZero)); err == nil || !strings.Contains(err.Error(), "unsupported movie version") {
+		t.Errorf("expected version 0 to be rejected, got %v", err)
+	}
+
+	if err := dmg.PlayMovie(m, true); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Frame != 1 {
+		t.Errorf("expected playback to start from the embedded state, at frame %d", dmg.Frame)
+	}
+	for range m.Frames {
+		dmg.RunFrame()
+	}
+	if dmg.MoviePlaying() || dmg.MovieError() != nil {
+		t.Errorf("unexpected playback outcome %v", dmg.MovieError())
+	}
+}
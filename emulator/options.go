@@ -0,0 +1,38 @@
+package emulator
+
+// Options Emulator configuration, given when creating the DMG
+type Options struct {
+	Model Model // Hardware model to emulate, drives boot state, colors & CGB features
+	Trace bool  // Print executed instructions
+	// FlatMemory Turn the bus into 64KB of plain RAM, without boot ROM, I/O registers or read only areas.
+	// Used to run CPU conformance tests.
+	FlatMemory bool
+}
+
+// DefaultOptions Options used by MakeDMG
+func DefaultOptions() Options {
+	return Options{
+		Model: ModelDMG,
+	}
+}
+
+// CGBMode Are CGB features enabled : requires a CGB compatible model & a cartridge flagged as CGB compatible.
+// Otherwise, CGB models run the cartridge in DMG compatibility mode.
+func (dmg *DMG) CGBMode() bool {
+	return dmg.Model.IsCGB() && dmg.Memory[CartridgeHeaderCGBFlag]&0x80 != 0
+}
+
+// isCGBRegister Is address an I/O register only available in CGB mode
+func isCGBRegister(address uint16) bool {
+	switch {
+	case address == 0xFF4D, address == 0xFF4F: // KEY1, VBK
+		return true
+	case address >= 0xFF51 && address <= 0xFF56: // HDMA1-5, RP
+		return true
+	case address >= 0xFF68 && address <= 0xFF6B: // BCPS, BCPD, OCPS, OCPD
+		return true
+	case address == 0xFF70: // SVBK
+		return true
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package emulator
+
+import "testing"
+
+func TestParseModel(t *testing.T) {
+	m, err := ParseModel("sgb2")
+	if err != nil || m != ModelSGB2 {
+		t.Errorf("expected SGB2, got %s (%v)", m, err)
+	}
+	if _, err := ParseModel("NES"); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+}
+
+func TestModelDetectionRegisters(t *testing.T) {
+	// Value of A games use to detect the hardware they run on
+	expected := map[Model]uint8{
+		ModelDMG:  0x01,
+		ModelMGB:  0xFF,
+		ModelSGB:  0x01,
+		ModelSGB2: 0xFF,
+		ModelCGB:  0x11,
+		ModelAGB:  0x11,
+	}
+	for model, a := range expected {
+		dmg := MakeDMGWithOptions(Options{Model: model})
+		dmg.SkipBoot()
+		if dmg.Gbz80.A() != a {
+			t.Errorf("%s : expected A to be 0x%02X, but was 0x%02X", model, a, dmg.Gbz80.A())
+		}
+	}
+}
+
+func TestCGBRegistersOutsideCGBMode(t *testing.T) {
+	dmg := MakeDMGWithOptions(Options{Model: ModelDMG})
+	dmg.SetMemoryU8(0xFF70, 0x02)
+	if dmg.GetMemoryU8(0xFF70) != 0xFF {
+		t.Error("expected SVBK to read 0xFF on a DMG")
+	}
+
+	dmg = MakeDMGWithOptions(Options{Model: ModelCGB})
+	dmg.Memory[CartridgeHeaderCGBFlag] = 0xC0
+	dmg.SetMemoryU8(0xFF70, 0x02)
+	if dmg.GetMemoryU8(0xFF70) != 0x02 {
+		t.Error("expected SVBK to be writable in CGB mode")
+	}
+}
@@ -0,0 +1,239 @@
+package emulator
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The PPU outputs shades (0 lightest to 3 darkest) through one of 3 palettes : background, sprite palettes 0 & 1.
+// A color scheme gives the colors of each shade for each palette, so that the look of DMG games can be changed at runtime.
+
+const (
+	PaletteBG   = 0
+	PaletteOBJ0 = 1
+	PaletteOBJ1 = 2
+)
+
+// Palette Colors of the 4 shades, lightest to darkest
+type Palette [4]color.RGBA
+
+// ColorScheme Palettes used to display DMG games
+type ColorScheme struct {
+	Name string
+	BG   Palette
+	OBJ0 Palette
+	OBJ1 Palette
+}
+
+// Palette Get palette PaletteBG, PaletteOBJ0 or PaletteOBJ1
+func (s ColorScheme) Palette(palette int) Palette {
+	switch palette {
+	case PaletteOBJ0:
+		return s.OBJ0
+	case PaletteOBJ1:
+		return s.OBJ1
+	}
+	return s.BG
+}
+
+// singlePaletteScheme Scheme using the same palette for background & sprites
+func singlePaletteScheme(name string, palette Palette) ColorScheme {
+	return ColorScheme{Name: name, BG: palette, OBJ0: palette, OBJ1: palette}
+}
+
+// rgb Palette from 0xRRGGBB colors
+func rgb(colors ...uint32) Palette {
+	var p Palette
+	for i, c := range colors {
+		p[i] = color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xFF}
+	}
+	return p
+}
+
+// ColorSchemes Built-in color schemes.
+// The CGB ones are the compatibility palettes the CGB boot ROM offers for DMG games, named after the button combination selecting them.
+// Right+A is the one it picks for games without a palette of their own.
+var ColorSchemes = []ColorScheme{
+	singlePaletteScheme("DMG Green", Palette(ModelDMG.DefaultShades())),
+	singlePaletteScheme("Pocket Grey", Palette(ModelMGB.DefaultShades())),
+	singlePaletteScheme("High Contrast", rgb(0xFFFFFF, 0xAAAAAA, 0x555555, 0x000000)),
+	singlePaletteScheme("SGB 1-A", Palette(ModelSGB.DefaultShades())),
+	singlePaletteScheme("CGB Up", rgb(0xFFFFFF, 0xFFAD63, 0x843100, 0x000000)),
+	{
+		Name: "CGB Up+A",
+		BG:   rgb(0xFFFFFF, 0xFF8484, 0x943A3A, 0x000000),
+		OBJ0: rgb(0xFFFFFF, 0x7BFF31, 0x008400, 0x000000),
+		OBJ1: rgb(0xFFFFFF, 0x63A5FF, 0x0000FF, 0x000000),
+	},
+	{
+		Name: "CGB Up+B",
+		BG:   rgb(0xFFE6C5, 0xCE9C84, 0x846B29, 0x5A3108),
+		OBJ0: rgb(0xFFFFFF, 0xFFAD63, 0x843100, 0x000000),
+		OBJ1: rgb(0xFFFFFF, 0xFFAD63, 0x843100, 0x000000),
+	},
+	{
+		Name: "CGB Left",
+		BG:   rgb(0xFFFFFF, 0x63A5FF, 0x0000FF, 0x000000),
+		OBJ0: rgb(0xFFFFFF, 0xFF8484, 0x943A3A, 0x000000),
+		OBJ1: rgb(0xFFFFFF, 0x7BFF31, 0x008400, 0x000000),
+	},
+	{
+		Name: "CGB Left+A",
+		BG:   rgb(0xFFFFFF, 0x8C8CDE, 0x52528C, 0x000000),
+		OBJ0: rgb(0xFFFFFF, 0xFF8484, 0x943A3A, 0x000000),
+		OBJ1: rgb(0xFFFFFF, 0xFFAD63, 0x843100, 0x000000),
+	},
+	singlePaletteScheme("CGB Left+B", rgb(0xFFFFFF, 0xA5A5A5, 0x525252, 0x000000)),
+	singlePaletteScheme("CGB Down", rgb(0xFFFFA5, 0xFF9494, 0x9494FF, 0x000000)),
+	singlePaletteScheme("CGB Down+A", rgb(0xFFFFFF, 0xFFFF00, 0xFF0000, 0x000000)),
+	{
+		Name: "CGB Down+B",
+		BG:   rgb(0xFFFFFF, 0xFFFF00, 0x7B4A00, 0x000000),
+		OBJ0: rgb(0xFFFFFF, 0x63A5FF, 0x0000FF, 0x000000),
+		OBJ1: rgb(0xFFFFFF, 0x7BFF31, 0x008400, 0x000000),
+	},
+	singlePaletteScheme("CGB Right", rgb(0xFFFFFF, 0x52FF00, 0xFF4200, 0x000000)),
+	{
+		Name: "CGB Right+A",
+		BG:   rgb(0xFFFFFF, 0x7BFF31, 0x0063C5, 0x000000),
+		OBJ0: rgb(0xFFFFFF, 0xFF8484, 0x943A3A, 0x000000),
+		OBJ1: rgb(0xFFFFFF, 0xFF8484, 0x943A3A, 0x000000),
+	},
+	singlePaletteScheme("CGB Right+B", rgb(0x000000, 0x008484, 0xFFDE00, 0xFFFFFF)),
+}
+
+// FindColorScheme Get the built-in scheme named name (case insensitive)
+func FindColorScheme(name string) (ColorScheme, bool) {
+	for _, s := range ColorSchemes {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return ColorScheme{}, false
+}
+
+// DefaultColorScheme Colors of the model screen, CGB models using the compatibility palette picked for unknown games
+func DefaultColorScheme(m Model) ColorScheme {
+	if m.IsCGB() {
+		s, _ := FindColorScheme("CGB Right+A")
+		return s
+	}
+	return singlePaletteScheme(m.String(), Palette(m.DefaultShades()))
+}
+
+// ReadColorScheme Read a color scheme file :
+//
+//	; Comment
+//	name = Autumn
+//	bg   = #FFF5DD #F2B36B #9C4A1A #2A1005
+//	obj0 = #FFFFFF #E0A060 #804020 #000000
+//	obj1 = #FFFFFF #A0C0E0 #405880 #000000
+//
+// Colors are given lightest to darkest, obj0 & obj1 default to bg when missing.
+func ReadColorScheme(r io.Reader) (ColorScheme, error) {
+	var s ColorScheme
+	found := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return s, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if key == "name" {
+			s.Name = value
+			continue
+		}
+		palette, err := parsePalette(value)
+		if err != nil {
+			return s, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch key {
+		case "bg":
+			s.BG = palette
+		case "obj0":
+			s.OBJ0 = palette
+		case "obj1":
+			s.OBJ1 = palette
+		default:
+			return s, fmt.Errorf("line %d: unknown key %q", line, key)
+		}
+		found[key] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	if !found["bg"] {
+		return s, fmt.Errorf("missing bg palette")
+	}
+	if !found["obj0"] {
+		s.OBJ0 = s.BG
+	}
+	if !found["obj1"] {
+		s.OBJ1 = s.BG
+	}
+	return s, nil
+}
+
+// LoadColorScheme Read the color scheme file at path, named after the file when it doesn't give a name
+func LoadColorScheme(path string) (ColorScheme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ColorScheme{}, err
+	}
+	defer f.Close()
+	s, err := ReadColorScheme(f)
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Name == "" {
+		s.Name = path
+	}
+	return s, nil
+}
+
+// parsePalette Parse 4 colors written as #RRGGBB
+func parsePalette(text string) (Palette, error) {
+	var p Palette
+	fields := strings.Fields(text)
+	if len(fields) != len(p) {
+		return p, fmt.Errorf("expected %d colors, got %d", len(p), len(fields))
+	}
+	for i, field := range fields {
+		hex := strings.TrimPrefix(field, "#")
+		c, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return p, fmt.Errorf("invalid color %q, expected #RRGGBB", field)
+		}
+		p[i] = color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xFF}
+	}
+	return p, nil
+}
+
+// SetColorScheme Change the colors used to display shades, the current frame being recolored at once
+func (dmg *DMG) SetColorScheme(s ColorScheme) {
+	dmg.colors = s
+	dmg.colorize()
+}
+
+// ColorScheme Get the colors used to display shades
+func (dmg *DMG) ColorScheme() ColorScheme {
+	return dmg.colors
+}
+
+// setPixel Output shade through palette for the pixel at index i
+func (dmg *DMG) setPixel(i int, palette int, shade uint8) {
+	dmg.pixels[i] = uint8(palette)<<2 | shade&0x03
+}
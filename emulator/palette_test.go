@@ -0,0 +1,77 @@
+package emulator
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestReadColorScheme(t *testing.T) {
+	s, err := ReadColorScheme(strings.NewReader(`
+; Autumn colors
+name = Autumn
+bg   = #FFF5DD #F2B36B #9C4A1A #2A1005
+obj1 = FFFFFF A0C0E0 405880 000000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Autumn" {
+		t.Errorf("unexpected name %q", s.Name)
+	}
+	if s.BG[1] != (color.RGBA{0xF2, 0xB3, 0x6B, 0xFF}) {
+		t.Errorf("unexpected BG shade 1 %v", s.BG[1])
+	}
+	if s.OBJ0 != s.BG {
+		t.Error("OBJ0 should default to BG")
+	}
+	if s.OBJ1[1] != (color.RGBA{0xA0, 0xC0, 0xE0, 0xFF}) {
+		t.Errorf("unexpected OBJ1 shade 1 %v", s.OBJ1[1])
+	}
+
+	for _, invalid := range []string{
+		"obj0 = #FFFFFF #AAAAAA #555555 #000000", // No BG
+		"bg = #FFFFFF #AAAAAA #555555",
+		"bg = #FFFFFF #AAAAAA #555555 #00000G",
+		"fg = #FFFFFF #AAAAAA #555555 #000000",
+		"bg",
+	} {
+		if _, err := ReadColorScheme(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSetColorScheme(t *testing.T) {
+	dmg := MakeDMG()
+	if dmg.ColorScheme().BG != Palette(ModelDMG.DefaultShades()) {
+		t.Error("DMG should default to its own shades")
+	}
+	if MakeDMGWithOptions(Options{Model: ModelCGB}).ColorScheme().Name != "CGB Right+A" {
+		t.Error("CGB should default to its compatibility palette")
+	}
+
+	dmg.setPixel(0, PaletteBG, 3)
+	dmg.setPixel(1, PaletteOBJ1, 2)
+	s, ok := FindColorScheme("cgb left")
+	if !ok {
+		t.Fatal("CGB Left scheme not found")
+	}
+	dmg.SetColorScheme(s)
+	if dmg.FrameBuffer().RGBAAt(0, 0) != s.BG[3] || dmg.FrameBuffer().RGBAAt(1, 0) != s.OBJ1[2] {
+		t.Error("screen not recolored with the new scheme")
+	}
+}
+
+func TestCGBCompatibilityPalettes(t *testing.T) {
+	for _, direction := range []string{"Up", "Left", "Down", "Right"} {
+		for _, button := range []string{"", "+A", "+B"} {
+			if _, ok := FindColorScheme("CGB " + direction + button); !ok {
+				t.Errorf("CGB %s%s scheme not found", direction, button)
+			}
+		}
+	}
+	if s, _ := FindColorScheme("CGB Right"); s.BG != s.OBJ0 || s.BG[1] != (color.RGBA{0x52, 0xFF, 0x00, 0xFF}) {
+		t.Errorf("CGB Right should use the same green & red palette everywhere, got %v", s)
+	}
+}
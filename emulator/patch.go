@@ -0,0 +1,325 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ROMs can be soft patched when loaded : the patch is applied to the ROM in memory, the file on disk is left untouched.
+// A patch named after the ROM (game.ips for game.gb) is applied automatically.
+//
+// IPS : "PATCH", then records until "EOF", optionally followed by the 3 bytes size the ROM is truncated to.
+//
+//	+-------------------+----------------------------------------------------+
+//	| Offset (3 bytes)  | Size (2 bytes), then Size bytes written at Offset  |
+//	| Offset (3 bytes)  | 0, then Count (2 bytes) & the byte repeated Count  |
+//	+-------------------+----------------------------------------------------+
+//
+// UPS : "UPS1", source & target sizes, then records until the footer : the distance skipped since the previous record,
+// then bytes XORed with the source until a 0 byte.
+//
+// BPS : "BPS1", source, target & metadata sizes, the metadata, then actions until the footer, building the target
+// from the source or the target itself : read or copy from either, at relative offsets.
+//
+// UPS & BPS sizes & offsets are variable length integers. Their 12 bytes footer holds the CRC32 of the source,
+// of the target and of the patch itself, all of them being checked.
+
+// PatchExtensions Extensions of the patch files, in the order they are looked for
+var PatchExtensions = []string{".ips", ".ups", ".bps"}
+
+// NoPatch Patch path disabling automatic patching
+const NoPatch = "none"
+
+// ErrPatchSourceMismatch Returned when a patch is applied to another ROM than the one it was made for
+var ErrPatchSourceMismatch = errors.New("patch was made for another ROM")
+
+// FindPatch Get the patch named after rom, next to it, "" when there is none
+func FindPatch(rom ROMFile) string {
+	name := strings.TrimSuffix(rom.Name, filepath.Ext(rom.Name))
+	for _, ext := range PatchExtensions {
+		path := filepath.Join(filepath.Dir(rom.Path), name+ext)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// PatchROM Apply the patch file at path to rom, the one found by FindPatch when path is empty, none for NoPatch
+func PatchROM(rom ROMFile, path string) (ROMFile, error) {
+	switch path {
+	case NoPatch:
+		return rom, nil
+	case "":
+		if path = FindPatch(rom); path == "" {
+			return rom, nil
+		}
+	}
+	patch, err := os.ReadFile(path)
+	if err != nil {
+		return rom, err
+	}
+	data, err := ApplyPatch(rom.Data, patch)
+	if err != nil {
+		return rom, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	rom.Data = data
+	rom.Patch = path
+	return rom, nil
+}
+
+// ApplyPatch Apply an IPS, UPS or BPS patch to rom, returning the patched copy
+func ApplyPatch(rom []uint8, patch []uint8) ([]uint8, error) {
+	switch {
+	case bytes.HasPrefix(patch, []byte("PATCH")):
+		return ApplyIPS(rom, patch)
+	case bytes.HasPrefix(patch, []byte("UPS1")):
+		return ApplyUPS(rom, patch)
+	case bytes.HasPrefix(patch, []byte("BPS1")):
+		return ApplyBPS(rom, patch)
+	}
+	return nil, errors.New("unknown patch format, expected IPS, UPS or BPS")
+}
+
+// errTruncatedPatch Returned when a patch ends in the middle of a record
+var errTruncatedPatch = errors.New("truncated patch")
+
+// ApplyIPS Apply an IPS patch to rom, returning the patched copy
+func ApplyIPS(rom []uint8, patch []uint8) ([]uint8, error) {
+	out := bytes.Clone(rom)
+	pos := 5
+	for {
+		if pos+3 > len(patch) {
+			return nil, errTruncatedPatch
+		}
+		if string(patch[pos:pos+3]) == "EOF" {
+			pos += 3
+			break
+		}
+		if pos+5 > len(patch) {
+			return nil, errTruncatedPatch
+		}
+		offset := int(patch[pos])<<16 | int(patch[pos+1])<<8 | int(patch[pos+2])
+		size := int(binary.BigEndian.Uint16(patch[pos+3:]))
+		pos += 5
+		var data []uint8
+		if size > 0 {
+			if pos+size > len(patch) {
+				return nil, errTruncatedPatch
+			}
+			data = patch[pos : pos+size]
+			pos += size
+		} else {
+			if pos+3 > len(patch) {
+				return nil, errTruncatedPatch
+			}
+			data = bytes.Repeat(patch[pos+2:pos+3], int(binary.BigEndian.Uint16(patch[pos:])))
+			pos += 3
+		}
+		if end := offset + len(data); end > len(out) {
+			if end > MemorySize {
+				return nil, fmt.Errorf("patched ROM too large: %d bytes", end)
+			}
+			out = append(out, make([]uint8, end-len(out))...)
+		}
+		copy(out[offset:], data)
+	}
+	if pos+3 <= len(patch) {
+		size := int(patch[pos])<<16 | int(patch[pos+1])<<8 | int(patch[pos+2])
+		out = out[:min(size, len(out))]
+	}
+	return out, nil
+}
+
+// beatPatch UPS & BPS patch being read
+type beatPatch struct {
+	data []uint8
+	pos  int
+	end  int // Start of the footer
+}
+
+// newBeatPatch Check the footer checksums of a UPS or BPS patch against the source
+func newBeatPatch(source []uint8, patch []uint8, magic string) (*beatPatch, uint32, error) {
+	if len(patch) < len(magic)+12 {
+		return nil, 0, errTruncatedPatch
+	}
+	end := len(patch) - 12
+	if crc32.ChecksumIEEE(patch[:len(patch)-4]) != binary.LittleEndian.Uint32(patch[end+8:]) {
+		return nil, 0, errors.New("corrupted patch: checksum mismatch")
+	}
+	sourceCRC := binary.LittleEndian.Uint32(patch[end:])
+	targetCRC := binary.LittleEndian.Uint32(patch[end+4:])
+	if crc := crc32.ChecksumIEEE(source); crc != sourceCRC {
+		if crc == targetCRC {
+			return nil, 0, fmt.Errorf("%w: the ROM is already patched", ErrPatchSourceMismatch)
+		}
+		return nil, 0, fmt.Errorf("%w: ROM CRC32 is %08X, expected %08X", ErrPatchSourceMismatch, crc, sourceCRC)
+	}
+	return &beatPatch{data: patch, pos: len(magic), end: end}, targetCRC, nil
+}
+
+// number Read a variable length integer
+func (p *beatPatch) number() (int, error) {
+	value, shift := 0, 1
+	for {
+		if p.pos >= p.end || shift > 1<<28 {
+			return 0, errTruncatedPatch
+		}
+		b := int(p.data[p.pos])
+		p.pos++
+		value += (b & 0x7F) * shift
+		if b&0x80 != 0 {
+			return value, nil
+		}
+		shift <<= 7
+		value += shift
+	}
+}
+
+// checkTarget Check the CRC32 of the patched ROM
+func checkTarget(target []uint8, crc uint32) ([]uint8, error) {
+	if got := crc32.ChecksumIEEE(target); got != crc {
+		return nil, fmt.Errorf("patched ROM CRC32 is %08X, expected %08X", got, crc)
+	}
+	return target, nil
+}
+
+// ApplyUPS Apply a UPS patch to rom, returning the patched copy
+func ApplyUPS(rom []uint8, patch []uint8) ([]uint8, error) {
+	p, targetCRC, err := newBeatPatch(rom, patch, "UPS1")
+	if err != nil {
+		return nil, err
+	}
+	sourceSize, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+	targetSize, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != len(rom) {
+		return nil, fmt.Errorf("%w: ROM is %d bytes, expected %d", ErrPatchSourceMismatch, len(rom), sourceSize)
+	}
+	if targetSize > MemorySize {
+		return nil, fmt.Errorf("patched ROM too large: %d bytes", targetSize)
+	}
+
+	target := make([]uint8, targetSize)
+	copy(target, rom)
+	offset := 0
+	for p.pos < p.end {
+		skip, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		offset += skip
+		for {
+			if p.pos >= p.end {
+				return nil, errTruncatedPatch
+			}
+			x := p.data[p.pos]
+			p.pos++
+			if offset < targetSize {
+				target[offset] ^= x
+			}
+			offset++
+			if x == 0 {
+				break
+			}
+		}
+	}
+	return checkTarget(target, targetCRC)
+}
+
+// BPS actions
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// ApplyBPS Apply a BPS patch to rom, returning the patched copy
+func ApplyBPS(rom []uint8, patch []uint8) ([]uint8, error) {
+	p, targetCRC, err := newBeatPatch(rom, patch, "BPS1")
+	if err != nil {
+		return nil, err
+	}
+	var sizes [3]int // Source, target & metadata
+	for i := range sizes {
+		if sizes[i], err = p.number(); err != nil {
+			return nil, err
+		}
+	}
+	if sizes[0] != len(rom) {
+		return nil, fmt.Errorf("%w: ROM is %d bytes, expected %d", ErrPatchSourceMismatch, len(rom), sizes[0])
+	}
+	if sizes[1] > MemorySize {
+		return nil, fmt.Errorf("patched ROM too large: %d bytes", sizes[1])
+	}
+	p.pos += sizes[2]
+
+	target := make([]uint8, sizes[1])
+	out, sourceRel, targetRel := 0, 0, 0
+	// relative Move a relative offset by the signed distance read from the patch
+	relative := func(offset int) (int, error) {
+		d, err := p.number()
+		if d&1 != 0 {
+			return offset - d>>1, err
+		}
+		return offset + d>>1, err
+	}
+	for p.pos < p.end {
+		action, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		length := action>>2 + 1
+		if out+length > len(target) {
+			return nil, errors.New("corrupted patch: writes past the end of the ROM")
+		}
+		switch action & 3 {
+		case bpsSourceRead:
+			if out+length > len(rom) {
+				return nil, errors.New("corrupted patch: reads past the end of the source ROM")
+			}
+			copy(target[out:], rom[out:out+length])
+		case bpsTargetRead:
+			if p.pos+length > p.end {
+				return nil, errTruncatedPatch
+			}
+			copy(target[out:], p.data[p.pos:p.pos+length])
+			p.pos += length
+		case bpsSourceCopy:
+			if sourceRel, err = relative(sourceRel); err != nil {
+				return nil, err
+			}
+			if sourceRel < 0 || sourceRel+length > len(rom) {
+				return nil, errors.New("corrupted patch: copies from outside the source ROM")
+			}
+			copy(target[out:], rom[sourceRel:sourceRel+length])
+			sourceRel += length
+		case bpsTargetCopy:
+			if targetRel, err = relative(targetRel); err != nil {
+				return nil, err
+			}
+			if targetRel < 0 || targetRel >= out {
+				return nil, errors.New("corrupted patch: copies from outside the patched ROM")
+			}
+			// Byte by byte, as the copy may overlap what it writes
+			for i := range length {
+				target[out+i] = target[targetRel+i]
+			}
+			targetRel += length
+		}
+		out += length
+	}
+	return checkTarget(target, targetCRC)
+}
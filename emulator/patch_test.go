@@ -0,0 +1,155 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// beatNumber Encode a UPS/BPS variable length integer
+func beatNumber(v int) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(out, b|0x80)
+		}
+		out = append(out, b)
+		v--
+	}
+}
+
+// beatFooter Append the UPS/BPS checksums to patch
+func beatFooter(patch []byte, source, target []uint8) []byte {
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(source))
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(target))
+	return binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(patch))
+}
+
+func TestApplyIPS(t *testing.T) {
+	rom := []uint8{0, 1, 2, 3, 4, 5}
+	patch := []byte("PATCH")
+	patch = append(patch, 0x00, 0x00, 0x01, 0x00, 0x02, 0xAA, 0xBB)       // 2 bytes at 1
+	patch = append(patch, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x03, 0xCC) // 0xCC 3 times at 6, growing the ROM
+	patch = append(patch, "EOF"...)
+	got, err := ApplyPatch(rom, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{0, 0xAA, 0xBB, 3, 4, 5, 0xCC, 0xCC, 0xCC}; !bytes.Equal(got, want) {
+		t.Errorf("got % X, want % X", got, want)
+	}
+	if rom[1] != 1 {
+		t.Error("the source ROM shouldn't be modified")
+	}
+
+	got, err = ApplyIPS(rom, append(bytes.Clone(patch), 0x00, 0x00, 0x04))
+	if err != nil || len(got) != 4 {
+		t.Errorf("expected the ROM to be truncated to 4 bytes, got % X, %v", got, err)
+	}
+	if _, err := ApplyIPS(rom, patch[:12]); err == nil {
+		t.Error("expected an error for a truncated patch")
+	}
+	// 0xCC 0xFFFF times at 0xFFFFFF
+	oversized := append([]byte("PATCH"), 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0xCC)
+	if _, err := ApplyIPS(rom, append(oversized, "EOF"...)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("expected an error for a patch growing the ROM past the address space, got %v", err)
+	}
+}
+
+func TestApplyUPS(t *testing.T) {
+	source := []uint8{0x10, 0x20, 0x30, 0x40}
+	target := []uint8{0x10, 0x21, 0x30, 0x40, 0x50}
+	patch := append([]byte("UPS1"), beatNumber(len(source))...)
+	patch = append(patch, beatNumber(len(target))...)
+	patch = append(patch, beatNumber(1)...)
+	patch = append(patch, 0x20^0x21, 0x00) // Offset 1
+	patch = append(patch, beatNumber(1)...)
+	patch = append(patch, 0x50, 0x00) // Offset 4, past the source
+	patch = beatFooter(patch, source, target)
+
+	got, err := ApplyPatch(source, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Errorf("got % X, want % X", got, target)
+	}
+	if _, err := ApplyUPS(target[:4], patch); !errors.Is(err, ErrPatchSourceMismatch) {
+		t.Errorf("expected a source mismatch, got %v", err)
+	}
+	patch[6] ^= 0xFF
+	if _, err := ApplyUPS(source, patch); err == nil {
+		t.Error("expected an error for a corrupted patch")
+	}
+}
+
+func TestApplyBPS(t *testing.T) {
+	source := []uint8{1, 2, 3, 4, 5, 6, 7, 8}
+	target := []uint8{1, 2, 9, 9, 9, 9, 5, 6}
+	action := func(kind, length int) []byte {
+		return beatNumber((length-1)<<2 | kind)
+	}
+	patch := append([]byte("BPS1"), beatNumber(len(source))...)
+	patch = append(patch, beatNumber(len(target))...)
+	patch = append(patch, beatNumber(4)...)
+	patch = append(patch, "meta"...)
+	patch = append(patch, action(bpsSourceRead, 2)...)
+	patch = append(patch, action(bpsTargetRead, 1)...)
+	patch = append(patch, 9)
+	patch = append(patch, action(bpsTargetCopy, 3)...)
+	patch = append(patch, beatNumber(2<<1)...) // Target offset 2, overlapping the copy
+	patch = append(patch, action(bpsSourceCopy, 2)...)
+	patch = append(patch, beatNumber(4<<1)...) // Source offset 4
+	patch = beatFooter(patch, source, target)
+
+	got, err := ApplyPatch(source, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Errorf("got % X, want % X", got, target)
+	}
+	if _, err := ApplyBPS(target, patch); !errors.Is(err, ErrPatchSourceMismatch) {
+		t.Errorf("expected a source mismatch, got %v", err)
+	}
+}
+
+func TestPatchROM(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.gb")
+	if err := os.WriteFile(path, []uint8{0, 1, 2}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rom, err := ReadROM(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched, err := PatchROM(rom, ""); err != nil || patched.Patch != "" {
+		t.Fatalf("expected no patch, got %q, %v", patched.Patch, err)
+	}
+
+	ips := filepath.Join(dir, "game.ips")
+	if err := os.WriteFile(ips, []byte("PATCH\x00\x00\x00\x00\x01\xFFEOF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	patched, err := PatchROM(rom, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Patch != ips || patched.Data[0] != 0xFF || rom.Data[0] != 0 {
+		t.Errorf("expected game.ips to be applied to a copy, got %+v", patched)
+	}
+	if patched, _ := PatchROM(rom, NoPatch); patched.Patch != "" {
+		t.Error("expected automatic patching to be disabled")
+	}
+	if data, _ := os.ReadFile(path); data[0] != 0 {
+		t.Error("the ROM file shouldn't be modified")
+	}
+}
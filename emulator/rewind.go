@@ -0,0 +1,189 @@
+package emulator
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+)
+
+// Rewind keeps recent save states in memory, so that emulation can go back in time.
+// Snapshots are grouped behind a keyframe : the keyframe holds a full state, the following snapshots
+// only hold their difference (XOR) with it, so they compress to almost nothing.
+// Oldest groups are dropped when the memory budget is exceeded.
+
+const (
+	DefaultRewindInterval = 4                // Frames between two snapshots
+	DefaultRewindBudget   = 64 * 1024 * 1024 // 64MB
+	rewindKeyframeEvery   = 30               // Snapshots in a keyframe group
+)
+
+// ErrNothingToRewind Returned by Rewind when no snapshot is available
+var ErrNothingToRewind = errors.New("no rewind snapshot available")
+
+// RewindOptions Rewind buffer configuration
+type RewindOptions struct {
+	Interval int // Frames between two snapshots
+	Budget   int // Maximum memory used by compressed snapshots, in bytes
+}
+
+type rewindSnapshot struct {
+	frame    uint64
+	keyframe bool
+	data     []byte // Compressed state, XORed with the group keyframe unless it is a keyframe
+}
+
+type rewindBuffer struct {
+	interval      int
+	budget        int
+	snapshots     []rewindSnapshot // Oldest first
+	size          int              // Compressed bytes held by snapshots
+	keyframe      []byte           // Uncompressed keyframe of the current group
+	sinceKeyframe int              // Snapshots taken since the current keyframe
+}
+
+// EnableRewind Start taking snapshots every options.Interval frames
+func (dmg *DMG) EnableRewind(options RewindOptions) {
+	if options.Interval <= 0 {
+		options.Interval = DefaultRewindInterval
+	}
+	if options.Budget <= 0 {
+		options.Budget = DefaultRewindBudget
+	}
+	dmg.rewind = &rewindBuffer{
+		interval: options.Interval,
+		budget:   options.Budget,
+	}
+}
+
+// DisableRewind Stop taking snapshots & release the memory they use
+func (dmg *DMG) DisableRewind() {
+	dmg.rewind = nil
+}
+
+// RewindBufferSize Memory used by rewind snapshots, in bytes
+func (dmg *DMG) RewindBufferSize() int {
+	if dmg.rewind == nil {
+		return 0
+	}
+	return dmg.rewind.size
+}
+
+// Rewind Go back in time by frames frames, to the closest snapshot.
+// When the buffer doesn't go that far, the oldest snapshot is restored.
+func (dmg *DMG) Rewind(frames int) error {
+	rb := dmg.rewind
+	if rb == nil || len(rb.snapshots) == 0 {
+		return ErrNothingToRewind
+	}
+
+	target := uint64(0)
+	if uint64(frames) < dmg.Frame {
+		target = dmg.Frame - uint64(frames)
+	}
+
+	// Most recent snapshot at or before target
+	index := 0
+	for i := len(rb.snapshots) - 1; i >= 0; i-- {
+		if rb.snapshots[i].frame <= target {
+			index = i
+			break
+		}
+	}
+
+	keyframeIndex := index
+	for !rb.snapshots[keyframeIndex].keyframe {
+		keyframeIndex--
+	}
+	keyframe, err := inflate(rb.snapshots[keyframeIndex].data)
+	if err != nil {
+		return err
+	}
+	state := keyframe
+	if index != keyframeIndex {
+		delta, err := inflate(rb.snapshots[index].data)
+		if err != nil {
+			return err
+		}
+		state = xorBytes(delta, keyframe)
+	}
+
+	if err := dmg.loadNativeState(bytes.NewReader(state)); err != nil {
+		return err
+	}
+
+	// Newer snapshots belong to a future that won't happen anymore
+	for i := index + 1; i < len(rb.snapshots); i++ {
+		rb.size -= len(rb.snapshots[i].data)
+		rb.snapshots[i] = rewindSnapshot{}
+	}
+	rb.snapshots = rb.snapshots[:index+1]
+	rb.keyframe = keyframe
+	rb.sinceKeyframe = index - keyframeIndex
+	dmg.rewindMovie()
+
+	return nil
+}
+
+// capture Take a snapshot of the machine
+func (rb *rewindBuffer) capture(dmg *DMG) {
+	var state bytes.Buffer
+	if err := dmg.writeState(&state); err != nil {
+		return
+	}
+
+	snapshot := rewindSnapshot{frame: dmg.Frame}
+	if rb.keyframe == nil || rb.sinceKeyframe+1 >= rewindKeyframeEvery || state.Len() != len(rb.keyframe) {
+		snapshot.keyframe = true
+		snapshot.data = deflate(state.Bytes())
+		rb.keyframe = state.Bytes()
+		rb.sinceKeyframe = 0
+	} else {
+		snapshot.data = deflate(xorBytes(state.Bytes(), rb.keyframe))
+		rb.sinceKeyframe++
+	}
+	rb.snapshots = append(rb.snapshots, snapshot)
+	rb.size += len(snapshot.data)
+
+	rb.evict()
+}
+
+// evict Drop the oldest keyframe groups until the buffer fits the budget, the current group is always kept
+func (rb *rewindBuffer) evict() {
+	for rb.size > rb.budget {
+		// End of the oldest group
+		end := 1
+		for end < len(rb.snapshots) && !rb.snapshots[end].keyframe {
+			end++
+		}
+		if end == len(rb.snapshots) {
+			return
+		}
+		for i := 0; i < end; i++ {
+			rb.size -= len(rb.snapshots[i].data)
+			rb.snapshots[i] = rewindSnapshot{}
+		}
+		rb.snapshots = rb.snapshots[end:]
+	}
+}
+
+// xorBytes XOR a & b, which must have the same length
+func xorBytes(a []byte, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func deflate(data []byte) []byte {
+	var out bytes.Buffer
+	w, _ := flate.NewWriter(&out, flate.BestSpeed)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return out.Bytes()
+}
+
+func inflate(data []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+}
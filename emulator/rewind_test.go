@@ -0,0 +1,64 @@
+package emulator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRewind(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00) // NOPs only
+	dmg.EnableRewind(RewindOptions{Interval: 1})
+
+	for i := 0; i < 40; i++ {
+		dmg.SetMemoryU8(WRAMStart, uint8(dmg.Frame))
+		dmg.RunFrame()
+	}
+	if dmg.Frame != 40 {
+		t.Fatalf("expected 40 frames to be run, got %d", dmg.Frame)
+	}
+
+	if err := dmg.Rewind(5); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Frame != 35 {
+		t.Errorf("expected to be back at frame 35, got %d", dmg.Frame)
+	}
+	if dmg.GetMemoryU8(WRAMStart) != 34 {
+		t.Errorf("expected WRAM to hold the value written during frame 34, got %d", dmg.GetMemoryU8(WRAMStart))
+	}
+
+	// Keep going back, across the keyframe group boundary
+	if err := dmg.Rewind(10); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Frame != 25 || dmg.GetMemoryU8(WRAMStart) != 24 {
+		t.Errorf("expected to be back at frame 25, got %d", dmg.Frame)
+	}
+}
+
+func TestRewindBudget(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	dmg.EnableRewind(RewindOptions{Interval: 1, Budget: 1})
+
+	for i := 0; i < 2*rewindKeyframeEvery+5; i++ {
+		dmg.RunFrame()
+	}
+	// Only the current group is kept
+	if len(dmg.rewind.snapshots) != 5 {
+		t.Errorf("expected older groups to be evicted, %d snapshots left", len(dmg.rewind.snapshots))
+	}
+
+	if err := dmg.Rewind(1000); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Frame != 2*rewindKeyframeEvery+1 {
+		t.Errorf("expected to rewind to the oldest snapshot, got frame %d", dmg.Frame)
+	}
+}
+
+func TestRewindDisabled(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	if err := dmg.Rewind(1); !errors.Is(err, ErrNothingToRewind) {
+		t.Errorf("expected ErrNothingToRewind, got %v", err)
+	}
+}
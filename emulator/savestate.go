@@ -0,0 +1,269 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Save state format :
+//
+//	+--------+----------------------------------------------+
+//	| Size   | Content                                      |
+//	+--------+----------------------------------------------+
+//	| 8      | Magic "GOGBSTAT"                             |
+//	| 2      | Format version                               |
+//	| 1      | Model                                        |
+//	| 4      | CRC32 of the ROM the state was captured with |
+//	| ...    | Chunks, until the "END " chunk               |
+//	+--------+----------------------------------------------+
+//
+// Each chunk is a 4 chars ID, followed by the payload length (uint32) & the payload.
+// New components get new chunks along with a new format version, so unknown chunks & oversized payloads are rejected.
+// All values are little endian.
+//
+// Timer, serial, DMA, PPU & APU are only emulated through their I/O registers for now,
+// so their state is part of the bus chunk.
+//
+// BESS blocks are appended after the "END " chunk so that other emulators can load the state (see bess.go).
+
+// Format versions : 1 holds the CPU & bus chunks, 2 adds the time chunk
+const (
+	SaveStateMagic   = "GOGBSTAT"
+	SaveStateVersion = 2
+)
+
+// Save state chunks IDs
+const (
+	stateChunkCPU  = "CPU "
+	stateChunkBus  = "BUS "
+	stateChunkTime = "TIME"
+	stateChunkEnd  = "END "
+)
+
+// stateChunkSizes Largest payload of each chunk
+var stateChunkSizes = map[string]int{
+	stateChunkCPU:  binary.Size(stateCPU{}),
+	stateChunkBus:  1 + MemorySize,
+	stateChunkTime: binary.Size(stateTime{}),
+}
+
+// ErrStateROMMismatch Returned when loading a state captured with a different ROM
+var ErrStateROMMismatch = errors.New("save state was captured with a different ROM")
+
+type stateHeader struct {
+	Magic       [8]byte
+	Version     uint16
+	Model       Model
+	ROMChecksum uint32
+}
+
+type stateCPU struct {
+	Af, Bc, De, Hl, Sp, Pc uint16
+	Ime, Halted            bool
+}
+
+type stateTime struct {
+	Cycles      uint64
+	Frame       uint64
+	FrameCycles uint32
+}
+
+// ROMChecksum CRC32 of the loaded ROM, used to identify it
+func (dmg *DMG) ROMChecksum() uint32 {
+	return crc32.ChecksumIEEE(dmg.ROM)
+}
+
+// SaveState Write the complete machine state to w
+func (dmg *DMG) SaveState(w io.Writer) error {
+	var state bytes.Buffer
+	if err := dmg.writeState(&state); err != nil {
+		return err
+	}
+	_, err := w.Write(state.Bytes())
+	return err
+}
+
+// writeState Write the native state followed by the BESS blocks
+func (dmg *DMG) writeState(w *bytes.Buffer) error {
+	header := stateHeader{
+		Version:     SaveStateVersion,
+		Model:       dmg.Model,
+		ROMChecksum: dmg.ROMChecksum(),
+	}
+	copy(header.Magic[:], SaveStateMagic)
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	cpu := stateCPU{
+		Af:     dmg.Gbz80.Af,
+		Bc:     dmg.Gbz80.Bc,
+		De:     dmg.Gbz80.De,
+		Hl:     dmg.Gbz80.Hl,
+		Sp:     dmg.Gbz80.Sp,
+		Pc:     dmg.Gbz80.Pc,
+		Ime:    dmg.Gbz80.Ime,
+		Halted: dmg.Gbz80.Halted,
+	}
+	if err := writeStateChunk(w, stateChunkCPU, cpu); err != nil {
+		return err
+	}
+
+	// Memory starts after the chunk ID, length & boot ROM flag
+	memory := uint32(w.Len() + 9)
+	var bus bytes.Buffer
+	bus.WriteByte(boolToU8(dmg.BootROMMapped))
+	bus.Write(dmg.Memory[:])
+	if err := writeStateChunk(w, stateChunkBus, bus.Bytes()); err != nil {
+		return err
+	}
+
+	timing := stateTime{
+		Cycles:      dmg.Cycles,
+		Frame:       dmg.Frame,
+		FrameCycles: uint32(dmg.frameCycles),
+	}
+	if err := writeStateChunk(w, stateChunkTime, timing); err != nil {
+		return err
+	}
+
+	if err := writeStateChunk(w, stateChunkEnd, []byte{}); err != nil {
+		return err
+	}
+
+	return dmg.writeBESS(w, memory)
+}
+
+// LoadState Restore the machine state previously written by SaveState, or a BESS state written by another emulator.
+// States captured with another ROM are rejected with ErrStateROMMismatch.
+func (dmg *DMG) LoadState(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, []byte(SaveStateMagic)) && bytes.HasSuffix(data, []byte(BESSMagic)) {
+		return dmg.LoadBESS(data)
+	}
+	return dmg.loadNativeState(bytes.NewReader(data))
+}
+
+// loadNativeState Restore the machine state from the native chunks
+func (dmg *DMG) loadNativeState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("invalid save state header: %w", err)
+	}
+	if string(header.Magic[:]) != SaveStateMagic {
+		return errors.New("not a save state")
+	}
+	if header.Version == 0 || header.Version > SaveStateVersion {
+		return fmt.Errorf("unsupported save state version %d (latest supported is %d)", header.Version, SaveStateVersion)
+	}
+	if int(header.Model) >= len(modelNames) {
+		return fmt.Errorf("invalid save state model %d", header.Model)
+	}
+	if header.Model != dmg.Model {
+		return fmt.Errorf("save state was captured on a %s, the emulated model is %s", header.Model, dmg.Model)
+	}
+	if header.ROMChecksum != dmg.ROMChecksum() {
+		return fmt.Errorf("%w (state ROM CRC32 %08X, loaded ROM CRC32 %08X)", ErrStateROMMismatch, header.ROMChecksum, dmg.ROMChecksum())
+	}
+
+	// Read every chunk before touching the machine, so a truncated state leaves it untouched
+	chunks := map[string][]byte{}
+	for {
+		var id [4]byte
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &id); err != nil {
+			return fmt.Errorf("truncated save state: %w", err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return fmt.Errorf("truncated save state: %w", err)
+		}
+		if string(id[:]) == stateChunkEnd {
+			break
+		}
+		size, known := stateChunkSizes[string(id[:])]
+		if !known {
+			return fmt.Errorf("invalid save state: unknown chunk %q", id)
+		}
+		if length > uint32(size) {
+			return fmt.Errorf("invalid save state: chunk %q is %d bytes, at most %d expected", id, length, size)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return fmt.Errorf("truncated save state chunk %q: %w", id, err)
+		}
+		chunks[string(id[:])] = payload
+	}
+
+	var cpu stateCPU
+	cpuChunk, ok := chunks[stateChunkCPU]
+	if !ok {
+		return errors.New("save state is missing the CPU chunk")
+	}
+	if err := binary.Read(bytes.NewReader(cpuChunk), binary.LittleEndian, &cpu); err != nil {
+		return fmt.Errorf("invalid CPU chunk: %w", err)
+	}
+	busChunk, ok := chunks[stateChunkBus]
+	if !ok || len(busChunk) != 1+MemorySize {
+		return errors.New("save state is missing the bus chunk")
+	}
+	var timing stateTime
+	timeChunk, ok := chunks[stateChunkTime]
+	if !ok && header.Version >= 2 {
+		return errors.New("save state is missing the time chunk")
+	}
+	if ok {
+		if err := binary.Read(bytes.NewReader(timeChunk), binary.LittleEndian, &timing); err != nil {
+			return fmt.Errorf("invalid time chunk: %w", err)
+		}
+		if timing.FrameCycles >= CyclesPerFrame {
+			return fmt.Errorf("invalid time chunk: %d cycles into the frame, a frame is %d", timing.FrameCycles, CyclesPerFrame)
+		}
+	}
+
+	dmg.Gbz80.Af = cpu.Af
+	dmg.Gbz80.Bc = cpu.Bc
+	dmg.Gbz80.De = cpu.De
+	dmg.Gbz80.Hl = cpu.Hl
+	dmg.Gbz80.Sp = cpu.Sp
+	dmg.Gbz80.Pc = cpu.Pc
+	dmg.Gbz80.Ime = cpu.Ime
+	dmg.Gbz80.Halted = cpu.Halted
+	dmg.BootROMMapped = busChunk[0] != 0 && len(dmg.BootROM) > 0
+	copy(dmg.Memory[:], busChunk[1:])
+	dmg.Cycles = timing.Cycles
+	dmg.Frame = timing.Frame
+	dmg.frameCycles = int(timing.FrameCycles)
+	dmg.forgetBreak()
+
+	return nil
+}
+
+// writeStateChunk Write a chunk with its ID & length, data being encoded with encoding/binary
+func writeStateChunk(w io.Writer, id string, data any) error {
+	var payload bytes.Buffer
+	if err := binary.Write(&payload, binary.LittleEndian, data); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, id); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(payload.Len())); err != nil {
+		return err
+	}
+	_, err := w.Write(payload.Bytes())
+	return err
+}
+
+func boolToU8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeTestDMG Create a DMG with a ROM filled with fill loaded
+func makeTestDMG(t *testing.T, fill uint8) *DMG {
+	t.Helper()
+	rom := bytes.Repeat([]uint8{fill}, 0x8000)
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, rom, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dmg := MakeDMG()
+	if err := dmg.LoadROM(path); err != nil {
+		t.Fatal(err)
+	}
+	dmg.SkipBoot()
+	return dmg
+}
+
+func TestSaveStateRoundTrip(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	dmg.Gbz80.SetR16Register(R16_BC, 0x1234)
+	dmg.Gbz80.Ime = true
+	dmg.SetMemoryU8(WRAMStart+0x10, 0x42)
+
+	var state bytes.Buffer
+	if err := dmg.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	dmg.Gbz80.SetR16Register(R16_BC, 0)
+	dmg.Gbz80.Ime = false
+	dmg.SetMemoryU8(WRAMStart+0x10, 0)
+
+	if err := dmg.LoadState(&state); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Gbz80.Bc != 0x1234 || !dmg.Gbz80.Ime {
+		t.Error("expected CPU registers to be restored")
+	}
+	if dmg.GetMemoryU8(WRAMStart+0x10) != 0x42 {
+		t.Error("expected WRAM to be restored")
+	}
+}
+
+func TestLoadStateFromOtherROM(t *testing.T) {
+	var state bytes.Buffer
+	if err := makeTestDMG(t, 0x00).SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	err := makeTestDMG(t, 0xFF).LoadState(&state)
+	if !errors.Is(err, ErrStateROMMismatch) {
+		t.Errorf("expected ErrStateROMMismatch, got %v", err)
+	}
+}
+
+func TestLoadStateTruncated(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	var state bytes.Buffer
+	if err := dmg.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	dmg.Gbz80.Pc = 0x1234
+	truncated := bytes.NewReader(state.Bytes()[:state.Len()/2])
+	if err := dmg.LoadState(truncated); err == nil {
+		t.Error("expected an error for a truncated state")
+	}
+	if dmg.Gbz80.Pc != 0x1234 {
+		t.Error("expected a failed load to leave the machine untouched")
+	}
+}
+
+func TestLoadStateInvalidHeaderAndChunks(t *testing.T) {
+	dmg := makeTestDMG(t, 0x00)
+	var state bytes.Buffer
+	if err := dmg.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	// The header is 15 bytes : magic, version, model & ROM checksum, the CPU chunk follows
+	for name, corrupt := range map[string]func(s []byte){
+		"version 0":       func(s []byte) { s[8], s[9] = 0, 0 },
+		"unknown model":   func(s []byte) { s[10] = 0xFF },
+		"other model":     func(s []byte) { s[10] = uint8(ModelCGB) },
+		"unknown chunk":   func(s []byte) { copy(s[15:], "ABCD") },
+		"oversized chunk": func(s []byte) { binary.LittleEndian.PutUint32(s[19:], 0xFFFFFFFF) },
+		"frame cycles": func(s []byte) {
+			timing := 15 + 8 + binary.Size(stateCPU{}) + 8 + 1 + MemorySize + 8
+			binary.LittleEndian.PutUint32(s[timing+16:], CyclesPerFrame)
+		},
+	} {
+		s := bytes.Clone(state.Bytes())
+		corrupt(s)
+		dmg.Gbz80.Pc = 0x1234
+		if err := dmg.LoadState(bytes.NewReader(s)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if dmg.Gbz80.Pc != 0x1234 || dmg.Model != ModelDMG {
+			t.Errorf("%s: expected a failed load to leave the machine untouched", name)
+		}
+	}
+}
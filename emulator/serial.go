@@ -0,0 +1,29 @@
+package emulator
+
+// Serial port, used by test ROMs to report their results
+// See : https://gbdev.io/pandocs/Serial_Data_Transfer_(Link_Cable).html
+
+const (
+	SerialDataReg      = 0xFF01 // SB
+	SerialControlReg   = 0xFF02 // SC
+	SerialInterruptBit = 0x08
+)
+
+// SerialOutput Bytes sent on the serial port since power on
+func (dmg *DMG) SerialOutput() []uint8 {
+	return dmg.serial
+}
+
+// writeSerialControl Handle a write to SC.
+// Without link cable partner, a transfer using the internal clock completes at once, shifting in 0xFF.
+// Transfers using the external clock never complete.
+func (dmg *DMG) writeSerialControl(value uint8) {
+	dmg.Memory[SerialControlReg] = value | 0x7E
+	if value&0x81 != 0x81 {
+		return
+	}
+	dmg.serial = append(dmg.serial, dmg.Memory[SerialDataReg])
+	dmg.Memory[SerialDataReg] = 0xFF
+	dmg.Memory[SerialControlReg] &^= 0x80
+	dmg.Memory[InterruptFlagReg] |= SerialInterruptBit
+}
@@ -0,0 +1,50 @@
+package emulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Speed Emulation speed, as a multiplier of the hardware rate
+type Speed float64
+
+const (
+	SpeedUnlimited Speed = 0 // Run frames as fast as possible
+	SpeedNormal    Speed = 1
+	MinSpeed       Speed = 0.25
+	MaxSpeed       Speed = 16
+)
+
+// Speeds Speeds offered to users, slowest first
+var Speeds = []Speed{0.25, 0.5, 1, 2, 4, 8, SpeedUnlimited}
+
+// ParseSpeed Parse a multiplier ("0.5", "2x") or "unlimited"
+func ParseSpeed(text string) (Speed, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "unlimited" || text == "max" {
+		return SpeedUnlimited, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(text, "x"), 64)
+	if err != nil || Speed(v) < MinSpeed || Speed(v) > MaxSpeed {
+		return SpeedNormal, fmt.Errorf("invalid speed %q, expected %gx to %gx or unlimited", text, MinSpeed, MaxSpeed)
+	}
+	return Speed(v), nil
+}
+
+// String Get the speed as "2x", or "unlimited"
+func (s Speed) String() string {
+	if s == SpeedUnlimited {
+		return "unlimited"
+	}
+	return strconv.FormatFloat(float64(s), 'g', -1, 64) + "x"
+}
+
+// FrameDuration Time a frame of model lasts at this speed, 0 when unlimited
+func (s Speed) FrameDuration(m Model) time.Duration {
+	if s == SpeedUnlimited {
+		return 0
+	}
+	return time.Duration(float64(time.Second*CyclesPerFrame) / float64(m.ClockSpeed()) / float64(s))
+}
@@ -0,0 +1,41 @@
+package emulator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpeed(t *testing.T) {
+	for text, expected := range map[string]Speed{
+		"1":         SpeedNormal,
+		"0.25":      MinSpeed,
+		"2x":        2,
+		"Unlimited": SpeedUnlimited,
+	} {
+		s, err := ParseSpeed(text)
+		if err != nil || s != expected {
+			t.Errorf("%q: expected %v, got %v (%v)", text, expected, s, err)
+		}
+	}
+	for _, invalid := range []string{"0", "0.1", "17", "fast"} {
+		if _, err := ParseSpeed(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+	if Speed(0.5).String() != "0.5x" || SpeedUnlimited.String() != "unlimited" {
+		t.Error("unexpected speed names")
+	}
+}
+
+func TestSpeedFrameDuration(t *testing.T) {
+	normal := SpeedNormal.FrameDuration(ModelDMG)
+	if normal < 16700*time.Microsecond || normal > 16800*time.Microsecond {
+		t.Errorf("expected a frame to last about 16.74ms, got %v", normal)
+	}
+	if d := Speed(2).FrameDuration(ModelDMG); d != normal/2 {
+		t.Errorf("expected frames twice as short at 2x, got %v", d)
+	}
+	if SpeedUnlimited.FrameDuration(ModelDMG) != 0 {
+		t.Error("unlimited frames shouldn't be paced")
+	}
+}
@@ -0,0 +1,37 @@
+package emutest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// Acid tests aren't shipped with the repository, they run when GB_TEST_ROMS points to a directory holding
+// dmg-acid2.gb & dmg-acid2.png (https://github.com/mattcurrie/dmg-acid2), cgb-acid2.gbc & cgb-acid2.png.
+
+func testROMPath(t *testing.T, name string) string {
+	dir := os.Getenv("GB_TEST_ROMS")
+	if dir == "" {
+		t.Skip("GB_TEST_ROMS not set")
+	}
+	return filepath.Join(dir, name)
+}
+
+func TestDMGAcid2(t *testing.T) {
+	AssertScreenshot(t, testROMPath(t, "dmg-acid2.gb"), testROMPath(t, "dmg-acid2.png"), ScreenshotOptions{
+		Model:     emulator.ModelDMG,
+		Frames:    60,
+		Palette:   ShadePalette(emulator.ModelDMG.DefaultShades(), ReferenceShades),
+		OutputDir: os.TempDir(),
+	})
+}
+
+func TestCGBAcid2(t *testing.T) {
+	AssertScreenshot(t, testROMPath(t, "cgb-acid2.gbc"), testROMPath(t, "cgb-acid2.png"), ScreenshotOptions{
+		Model:     emulator.ModelCGB,
+		Frames:    60,
+		OutputDir: os.TempDir(),
+	})
+}
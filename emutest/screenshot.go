@@ -0,0 +1,153 @@
+// Package emutest Helpers for tests running ROMs through the emulator.
+// Screenshot tests (dmg-acid2, cgb-acid2...) run a ROM then compare the LCD to a reference PNG, pixel per pixel.
+package emutest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"khopa.github.io/gogbemulator/emulator"
+	"khopa.github.io/gogbemulator/headless"
+)
+
+// ReferenceShades Shades used by most reference screenshots (dmg-acid2, mealybug...), lightest to darkest
+var ReferenceShades = [4]color.RGBA{
+	{0xFF, 0xFF, 0xFF, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+	{0x00, 0x00, 0x00, 0xFF},
+}
+
+// diffColor Color of the mismatching pixels in diff images
+var diffColor = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+
+// ScreenshotOptions Screenshot test configuration
+type ScreenshotOptions struct {
+	Model     emulator.Model            // Hardware the ROM runs on
+	Frames    int                       // Frames to run before taking the screenshot
+	Palette   map[color.RGBA]color.RGBA // Emulator colors to reference colors, other colors being compared as is
+	OutputDir string                    // Where actual & diff images are written on mismatch, the reference directory when empty
+}
+
+// ShadePalette Map the 4 shades of from to the ones of to
+func ShadePalette(from [4]color.RGBA, to [4]color.RGBA) map[color.RGBA]color.RGBA {
+	palette := map[color.RGBA]color.RGBA{}
+	for i := range from {
+		palette[from[i]] = to[i]
+	}
+	return palette
+}
+
+// MapPalette Copy img, replacing its colors as given by palette
+func MapPalette(img image.Image, palette map[color.RGBA]color.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			if mapped, ok := palette[c]; ok {
+				c = mapped
+			}
+			out.SetRGBA(x, y, c)
+		}
+	}
+	return out
+}
+
+// CompareImages Count the pixels differing between actual & reference.
+// The diff image shows the mismatching pixels in red over a faded copy of the reference.
+func CompareImages(actual image.Image, reference image.Image) (int, *image.RGBA, error) {
+	if actual.Bounds().Size() != reference.Bounds().Size() {
+		return 0, nil, fmt.Errorf("size mismatch: got %v, reference is %v", actual.Bounds().Size(), reference.Bounds().Size())
+	}
+	size := reference.Bounds().Size()
+	diff := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	mismatches := 0
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			a := color.RGBAModel.Convert(actual.At(actual.Bounds().Min.X+x, actual.Bounds().Min.Y+y)).(color.RGBA)
+			r := color.RGBAModel.Convert(reference.At(reference.Bounds().Min.X+x, reference.Bounds().Min.Y+y)).(color.RGBA)
+			if a != r {
+				mismatches++
+				diff.SetRGBA(x, y, diffColor)
+				continue
+			}
+			// Fade towards white, so that the red pixels stand out
+			diff.SetRGBA(x, y, color.RGBA{0xC0 + r.R/4, 0xC0 + r.G/4, 0xC0 + r.B/4, 0xFF})
+		}
+	}
+	return mismatches, diff, nil
+}
+
+// ReadPNG Decode the PNG file at path
+func ReadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// WritePNG Encode img as a PNG file at path
+func WritePNG(img image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AssertScreenshot Run the ROM at romPath, then check that its LCD matches the reference PNG.
+// The test is skipped when the ROM is missing, test ROMs being supplied locally.
+// On mismatch, <reference>-actual.png & <reference>-diff.png are written to options.OutputDir.
+func AssertScreenshot(t testing.TB, romPath string, referencePath string, options ScreenshotOptions) {
+	t.Helper()
+	if _, err := os.Stat(romPath); err != nil {
+		t.Skipf("test ROM not available: %v", err)
+	}
+
+	dmg := emulator.MakeDMGWithOptions(emulator.Options{Model: options.Model})
+	if err := dmg.LoadROM(romPath); err != nil {
+		t.Fatal(err)
+	}
+	dmg.Reset()
+	if _, err := headless.Run(dmg, headless.Options{Frames: options.Frames}); err != nil {
+		t.Fatal(err)
+	}
+
+	reference, err := ReadPNG(referencePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := MapPalette(dmg.Snapshot(), options.Palette)
+	mismatches, diff, err := CompareImages(actual, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatches == 0 {
+		return
+	}
+
+	dir := options.OutputDir
+	if dir == "" {
+		dir = filepath.Dir(referencePath)
+	}
+	base := filepath.Join(dir, strings.TrimSuffix(filepath.Base(referencePath), filepath.Ext(referencePath)))
+	for suffix, img := range map[string]image.Image{"-actual.png": actual, "-diff.png": diff} {
+		if err := WritePNG(img, base+suffix); err != nil {
+			t.Errorf("error writing %s: %v", base+suffix, err)
+		}
+	}
+	t.Errorf("%s: %d pixels differ from %s, see %s-actual.png & %s-diff.png", filepath.Base(romPath), mismatches, referencePath, base, base)
+}
@@ -0,0 +1,136 @@
+package emutest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"khopa.github.io/gogbemulator/emulator"
+	"khopa.github.io/gogbemulator/headless"
+)
+
+// recorder testing.TB catching failures, to test the helpers failing
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// Fatal Record the failure, then stop the goroutine as testing.T does
+func (r *recorder) Fatal(args ...any) {
+	r.failures = append(r.failures, fmt.Sprint(args...))
+	runtime.Goexit()
+}
+
+// run Call fn with the recorder in its own goroutine, Fatal stopping it
+func (r *recorder) run(fn func(t testing.TB)) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+}
+
+func TestCompareImages(t *testing.T) {
+	reference := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	actual := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	actual.SetRGBA(1, 2, color.RGBA{0x12, 0x34, 0x56, 0xFF})
+
+	mismatches, diff, err := CompareImages(actual, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatches != 1 || diff.RGBAAt(1, 2) != diffColor || diff.RGBAAt(0, 0) == diffColor {
+		t.Errorf("expected a single mismatch at (1,2), got %d", mismatches)
+	}
+
+	if _, _, err := CompareImages(image.NewRGBA(image.Rect(0, 0, 2, 2)), reference); err == nil {
+		t.Error("expected an error for images of different sizes")
+	}
+}
+
+func TestMapPalette(t *testing.T) {
+	shades := emulator.ModelDMG.DefaultShades()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for i, shade := range shades {
+		img.SetRGBA(i, 0, shade)
+	}
+	mapped := MapPalette(img, ShadePalette(shades, ReferenceShades))
+	for i, shade := range ReferenceShades {
+		if mapped.RGBAAt(i, 0) != shade {
+			t.Errorf("shade %d: expected %v, got %v", i, shade, mapped.RGBAAt(i, 0))
+		}
+	}
+}
+
+func TestAssertScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	romPath := filepath.Join(dir, "test.gb")
+	rom := make([]uint8, 0x8000)
+	copy(rom[emulator.CartridgeHeaderEntryPoint:], []uint8{0x18, 0xFE}) // JR -2
+	if err := os.WriteFile(romPath, rom, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	options := ScreenshotOptions{
+		Frames:  2,
+		Palette: ShadePalette(emulator.ModelDMG.DefaultShades(), ReferenceShades),
+	}
+
+	// Reference taken from the emulator itself
+	dmg := emulator.MakeDMG()
+	if err := dmg.LoadROM(romPath); err != nil {
+		t.Fatal(err)
+	}
+	dmg.Reset()
+	if _, err := headless.Run(dmg, headless.Options{Frames: options.Frames}); err != nil {
+		t.Fatal(err)
+	}
+	reference := MapPalette(dmg.Snapshot(), options.Palette)
+	referencePath := filepath.Join(dir, "reference.png")
+	if err := WritePNG(reference, referencePath); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recorder{TB: t}
+	r.run(func(t testing.TB) {
+		AssertScreenshot(t, romPath, referencePath, options)
+	})
+	if len(r.failures) != 0 {
+		t.Fatalf("expected screenshots to match: %v", r.failures)
+	}
+
+	reference.SetRGBA(10, 10, color.RGBA{0x12, 0x34, 0x56, 0xFF})
+	if err := WritePNG(reference, referencePath); err != nil {
+		t.Fatal(err)
+	}
+	r = &recorder{TB: t}
+	r.run(func(t testing.TB) {
+		AssertScreenshot(t, romPath, referencePath, options)
+	})
+	if len(r.failures) != 1 {
+		t.Fatalf("expected a single failure, got %v", r.failures)
+	}
+	for _, name := range []string{"reference-actual.png", "reference-diff.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s should be written on mismatch: %v", name, err)
+		}
+	}
+
+	r = &recorder{TB: t}
+	r.run(func(t testing.TB) {
+		AssertScreenshot(t, romPath, filepath.Join(dir, "missing.png"), options)
+	})
+	if len(r.failures) != 1 {
+		t.Errorf("expected a missing reference to stop the assertion, got %v", r.failures)
+	}
+}
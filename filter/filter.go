@@ -0,0 +1,297 @@
+// Package filter post-processes emulator frames on the CPU, between the DMG screen and the displayed image :
+// pixel art scalers (Scale2x, Scale3x, an hqx-style scaler), an LCD pixel grid & frame blending ghosting.
+package filter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Filter Turn a frame into a new image, src is left untouched
+type Filter interface {
+	Apply(src *image.RGBA) *image.RGBA
+}
+
+// Pipeline Filters applied one after the other
+type Pipeline []Filter
+
+// Apply Run src through every filter of the pipeline
+func (p Pipeline) Apply(src *image.RGBA) *image.RGBA {
+	for _, f := range p {
+		src = f.Apply(src)
+	}
+	return src
+}
+
+// Scaler Filter enlarging frames a whole number of times
+type Scaler interface {
+	Factor() int
+}
+
+// Factor Get how many times larger than its source the output of the pipeline is
+func (p Pipeline) Factor() int {
+	factor := 1
+	for _, f := range p {
+		if s, ok := f.(Scaler); ok {
+			factor *= s.Factor()
+		}
+	}
+	return factor
+}
+
+// Names Scaling filters offered to users, see New
+var Names = []string{"Scale2x", "Scale3x", "HQ2x", "LCD Grid"}
+
+// New Create the scaling filter named name, with its default settings
+func New(name string) (Filter, error) {
+	switch name {
+	case "Scale2x":
+		return Scale2x{}, nil
+	case "Scale3x":
+		return Scale3x{}, nil
+	case "HQ2x":
+		return HQ2x{}, nil
+	case "LCD Grid":
+		return LCDGrid{Scale: 3, Darken: 0.25}, nil
+	}
+	return nil, fmt.Errorf("unknown filter %q", name)
+}
+
+// at Get the pixel at x, y, coordinates out of src being clamped to its edges
+func at(src *image.RGBA, x, y int) color.RGBA {
+	b := src.Bounds()
+	x = min(max(x, b.Min.X), b.Max.X-1)
+	y = min(max(y, b.Min.Y), b.Max.Y-1)
+	return src.RGBAAt(x, y)
+}
+
+// newScaled Create an image scale times larger than src
+func newScaled(src *image.RGBA, scale int) *image.RGBA {
+	return image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx()*scale, src.Bounds().Dy()*scale))
+}
+
+// Scale2x Double the size, rounding diagonal edges instead of showing stairs (AdvMAME2x / EPX)
+type Scale2x struct{}
+
+func (Scale2x) Factor() int { return 2 }
+
+func (Scale2x) Apply(src *image.RGBA) *image.RGBA {
+	dst := newScaled(src, 2)
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			//   B
+			// D E F
+			//   H
+			B, D, E, F, H := at(src, x, y-1), at(src, x-1, y), at(src, x, y), at(src, x+1, y), at(src, x, y+1)
+			e0, e1, e2, e3 := E, E, E, E
+			if B != H && D != F {
+				if D == B {
+					e0 = D
+				}
+				if B == F {
+					e1 = F
+				}
+				if D == H {
+					e2 = D
+				}
+				if H == F {
+					e3 = F
+				}
+			}
+			dx, dy := (x-b.Min.X)*2, (y-b.Min.Y)*2
+			dst.SetRGBA(dx, dy, e0)
+			dst.SetRGBA(dx+1, dy, e1)
+			dst.SetRGBA(dx, dy+1, e2)
+			dst.SetRGBA(dx+1, dy+1, e3)
+		}
+	}
+	return dst
+}
+
+// Scale3x Triple the size, rounding diagonal edges instead of showing stairs (AdvMAME3x)
+type Scale3x struct{}
+
+func (Scale3x) Factor() int { return 3 }
+
+func (Scale3x) Apply(src *image.RGBA) *image.RGBA {
+	dst := newScaled(src, 3)
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// A B C
+			// D E F
+			// G H I
+			A, B, C := at(src, x-1, y-1), at(src, x, y-1), at(src, x+1, y-1)
+			D, E, F := at(src, x-1, y), at(src, x, y), at(src, x+1, y)
+			G, H, I := at(src, x-1, y+1), at(src, x, y+1), at(src, x+1, y+1)
+			out := [9]color.RGBA{E, E, E, E, E, E, E, E, E}
+			if B != H && D != F {
+				if D == B {
+					out[0] = D
+				}
+				if (D == B && E != C) || (B == F && E != A) {
+					out[1] = B
+				}
+				if B == F {
+					out[2] = F
+				}
+				if (D == B && E != G) || (D == H && E != A) {
+					out[3] = D
+				}
+				if (B == F && E != I) || (H == F && E != C) {
+					out[5] = F
+				}
+				if D == H {
+					out[6] = D
+				}
+				if (D == H && E != I) || (H == F && E != G) {
+					out[7] = H
+				}
+				if H == F {
+					out[8] = F
+				}
+			}
+			dx, dy := (x-b.Min.X)*3, (y-b.Min.Y)*3
+			for i, c := range out {
+				dst.SetRGBA(dx+i%3, dy+i/3, c)
+			}
+		}
+	}
+	return dst
+}
+
+// HQ2x Double the size, blending edges between similar colors like the hqx filters do.
+// This is a simplified take on hq2x : corners are interpolated from the neighbours they touch,
+// using the hqx color similarity test, instead of going through the full 256 patterns table.
+type HQ2x struct{}
+
+func (HQ2x) Factor() int { return 2 }
+
+func (HQ2x) Apply(src *image.RGBA) *image.RGBA {
+	dst := newScaled(src, 2)
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			e := at(src, x, y)
+			dx, dy := (x-b.Min.X)*2, (y-b.Min.Y)*2
+			for corner := 0; corner < 4; corner++ {
+				sx, sy := corner%2*2-1, corner/2*2-1 // Direction of the corner
+				h := at(src, x+sx, y)                // Horizontal neighbour
+				v := at(src, x, y+sy)                // Vertical neighbour
+				d := at(src, x+sx, y+sy)             // Diagonal neighbour
+				c := e
+				switch {
+				case similar(h, v) && !similar(e, h):
+					// Diagonal edge crossing the corner
+					c = blend(e, 2, h, 1, v, 1)
+				case similar(e, h) && similar(e, v) && !similar(e, d):
+					// Corner of a shape, slightly smoothed
+					c = blend(e, 3, d, 1, d, 0)
+				}
+				dst.SetRGBA(dx+corner%2, dy+corner/2, c)
+			}
+		}
+	}
+	return dst
+}
+
+// similar Are a & b too close to tell apart, with the hqx thresholds on Y, U & V
+func similar(a, b color.RGBA) bool {
+	ya, ua, va := yuv(a)
+	yb, ub, vb := yuv(b)
+	return abs(ya-yb) <= 48 && abs(ua-ub) <= 7 && abs(va-vb) <= 6
+}
+
+func yuv(c color.RGBA) (int, int, int) {
+	r, g, b := int(c.R), int(c.G), int(c.B)
+	return (r + g + b) >> 2, 128 + (r-b)>>2, 128 + (2*g-r-b)>>3
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// blend Weighted mean of 3 colors
+func blend(c1 color.RGBA, w1 int, c2 color.RGBA, w2 int, c3 color.RGBA, w3 int) color.RGBA {
+	w := w1 + w2 + w3
+	mix := func(a, b, c uint8) uint8 {
+		return uint8((int(a)*w1 + int(b)*w2 + int(c)*w3) / w)
+	}
+	return color.RGBA{mix(c1.R, c2.R, c3.R), mix(c1.G, c2.G, c3.G), mix(c1.B, c2.B, c3.B), 0xFF}
+}
+
+// LCDGrid Enlarge pixels Scale times, darkening their right & bottom edges to show the gaps between LCD cells
+type LCDGrid struct {
+	Scale  int
+	Darken float64 // 0 hides the grid, 1 draws it black
+}
+
+func (g LCDGrid) Factor() int { return max(g.Scale, 2) }
+
+func (g LCDGrid) Apply(src *image.RGBA) *image.RGBA {
+	scale := g.Factor()
+	keep := 1 - min(max(g.Darken, 0), 1)
+	dst := newScaled(src, scale)
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := src.RGBAAt(x, y)
+			gap := color.RGBA{uint8(float64(c.R) * keep), uint8(float64(c.G) * keep), uint8(float64(c.B) * keep), c.A}
+			dx, dy := (x-b.Min.X)*scale, (y-b.Min.Y)*scale
+			for j := 0; j < scale; j++ {
+				for i := 0; i < scale; i++ {
+					if i == scale-1 || j == scale-1 {
+						dst.SetRGBA(dx+i, dy+j, gap)
+					} else {
+						dst.SetRGBA(dx+i, dy+j, c)
+					}
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Ghosting Blend each frame with the previous output, like the slow responding DMG LCD does.
+// Games flickering sprites every other frame rely on it for transparency effects.
+type Ghosting struct {
+	Strength float64 // Weight of the previous output, 0 disables the effect
+	previous *image.RGBA
+}
+
+// NewGhosting Create a ghosting filter, 0.5 being close to a DMG screen
+func NewGhosting(strength float64) *Ghosting {
+	return &Ghosting{Strength: strength}
+}
+
+func (g *Ghosting) Apply(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	b := src.Bounds()
+	if g.previous == nil || g.previous.Bounds() != dst.Bounds() {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			copy(dst.Pix[dst.PixOffset(0, y-b.Min.Y):], src.Pix[src.PixOffset(b.Min.X, y):src.PixOffset(b.Max.X, y)])
+		}
+	} else {
+		strength := min(max(g.Strength, 0), 1)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			cur := src.Pix[src.PixOffset(b.Min.X, y):src.PixOffset(b.Max.X, y)]
+			i := dst.PixOffset(0, y-b.Min.Y)
+			for j, v := range cur {
+				prev := float64(g.previous.Pix[i+j])
+				dst.Pix[i+j] = uint8(float64(v) + (prev-float64(v))*strength + 0.5)
+			}
+		}
+	}
+	g.previous = dst
+	return dst
+}
+
+// Reset Forget the previous output, after a ROM change or a jump in time
+func (g *Ghosting) Reset() {
+	g.previous = nil
+}
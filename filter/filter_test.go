@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var (
+	white = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	black = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+// makeImage Build an image from rows of '#' (black) & '.' (white)
+func makeImage(rows ...string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				img.SetRGBA(x, y, black)
+			} else {
+				img.SetRGBA(x, y, white)
+			}
+		}
+	}
+	return img
+}
+
+func TestScalers(t *testing.T) {
+	// Staircase : scalers fill the inner corners of the diagonal
+	src := makeImage(
+		"#..",
+		"##.",
+		"###",
+	)
+	for _, name := range Names {
+		f, err := New(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := f.Apply(src)
+		factor := Pipeline{NewGhosting(0.5), f}.Factor()
+		if factor < 2 || dst.Bounds().Dx() != 3*factor || dst.Bounds().Dy() != 3*factor {
+			t.Errorf("%s: unexpected size %v for a %dx factor", name, dst.Bounds(), factor)
+		}
+	}
+
+	dst := Scale2x{}.Apply(src)
+	if dst.RGBAAt(2, 1) != black {
+		t.Error("Scale2x: expected the lower left quarter of the top middle pixel to be filled")
+	}
+	if dst.RGBAAt(1, 0) != black || dst.RGBAAt(5, 4) != black {
+		t.Error("Scale2x: pixels on the diagonal should keep their color")
+	}
+
+	dst = Scale3x{}.Apply(src)
+	if dst.RGBAAt(3, 2) != black {
+		t.Error("Scale3x: expected the lower left corner of the top middle pixel to be filled")
+	}
+	if dst.RGBAAt(4, 4) != black {
+		t.Error("Scale3x: center pixels should keep their color")
+	}
+
+	if _, err := New("Bilinear"); err == nil {
+		t.Error("expected an error for an unknown filter")
+	}
+}
+
+func TestHQ2x(t *testing.T) {
+	dst := HQ2x{}.Apply(makeImage(
+		"#..",
+		"##.",
+		"###",
+	))
+	// The upper right corner of the middle pixel is on the diagonal edge, between black & white
+	if c := dst.RGBAAt(3, 2); c == black || c == white {
+		t.Errorf("expected an interpolated color, got %v", c)
+	}
+	if dst.RGBAAt(2, 3) != black || dst.RGBAAt(5, 0) != white {
+		t.Error("pixels away from edges should keep their color")
+	}
+}
+
+func TestLCDGrid(t *testing.T) {
+	dst := LCDGrid{Scale: 3, Darken: 0.5}.Apply(makeImage("."))
+	if dst.Bounds().Dx() != 3 {
+		t.Fatalf("unexpected size %v", dst.Bounds())
+	}
+	if dst.RGBAAt(0, 0) != white || dst.RGBAAt(1, 1) != white {
+		t.Error("inside of the cell should keep its color")
+	}
+	if c := dst.RGBAAt(2, 0); c.R != 0x7F || c.A != 0xFF {
+		t.Errorf("expected a darkened gap, got %v", c)
+	}
+}
+
+func TestGhosting(t *testing.T) {
+	g := NewGhosting(0.5)
+	light, dark := makeImage("."), makeImage("#")
+	if g.Apply(light).RGBAAt(0, 0) != white {
+		t.Error("first frame should be shown as is")
+	}
+	// Sprites flickering every other frame end up half transparent
+	if c := (Pipeline{g}).Apply(dark).RGBAAt(0, 0); c.R != 0x80 {
+		t.Errorf("expected a blend of both frames, got %v", c)
+	}
+	g.Reset()
+	if g.Apply(dark).RGBAAt(0, 0) != black {
+		t.Error("previous frame should be forgotten after a reset")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"image"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"khopa.github.io/gogbemulator/filter"
+)
+
+const (
+	filterPreference   = "display.filter"
+	ghostingPreference = "display.ghosting"
+	ghostingStrength   = 0.5
+)
+
+// filterMenu "Filter" menu : post-processing of frames before they are displayed.
+// Frames are filtered on the emulation goroutine, while the menu changes filters from the UI one.
+type filterMenu struct {
+	prefs     fyne.Preferences
+	mu        sync.Mutex
+	name      string // Scaling filter, none when empty
+	ghosting  *filter.Ghosting
+	pipeline  filter.Pipeline
+	items     []*fyne.MenuItem // None, then a scaling filter per name
+	ghostItem *fyne.MenuItem
+	onMenu    func()           // Called when menu items need a refresh
+	onFactor  func(factor int) // Called when the scaling filter changes, with how many times larger frames get
+}
+
+// newFilterMenu Create the filters, restoring the ones saved in preferences
+func newFilterMenu(prefs fyne.Preferences) *filterMenu {
+	m := &filterMenu{prefs: prefs}
+	m.name = prefs.String(filterPreference)
+	if prefs.Bool(ghostingPreference) {
+		m.ghosting = filter.NewGhosting(ghostingStrength)
+	}
+	m.build()
+	return m
+}
+
+// Apply Filter a frame
+func (m *filterMenu) Apply(frame image.Image) image.Image {
+	rgba, ok := frame.(*image.RGBA)
+	if !ok {
+		return frame
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pipeline.Apply(rgba)
+}
+
+// Factor Get how many times larger than the LCD filtered frames are
+func (m *filterMenu) Factor() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pipeline.Factor()
+}
+
+// Reset Forget previous frames, after loading a ROM
+func (m *filterMenu) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ghosting != nil {
+		m.ghosting.Reset()
+	}
+}
+
+// SetFilter Use the scaling filter named name, or none when empty
+func (m *filterMenu) SetFilter(name string) {
+	m.mu.Lock()
+	m.name = name
+	m.build()
+	m.mu.Unlock()
+	m.prefs.SetString(filterPreference, name)
+	m.refresh()
+	if m.onFactor != nil {
+		m.onFactor(m.Factor())
+	}
+}
+
+// SetGhosting Enable or disable frame blending
+func (m *filterMenu) SetGhosting(enabled bool) {
+	m.mu.Lock()
+	m.ghosting = nil
+	if enabled {
+		m.ghosting = filter.NewGhosting(ghostingStrength)
+	}
+	m.build()
+	m.mu.Unlock()
+	m.prefs.SetBool(ghostingPreference, enabled)
+	m.refresh()
+}
+
+// build Assemble the pipeline : ghosting works on the LCD pixels, so it goes before scaling
+func (m *filterMenu) build() {
+	m.pipeline = nil
+	if m.ghosting != nil {
+		m.pipeline = append(m.pipeline, m.ghosting)
+	}
+	if m.name != "" {
+		f, err := filter.New(m.name)
+		if err != nil {
+			// Filter removed since it was saved in preferences
+			m.name = ""
+			return
+		}
+		m.pipeline = append(m.pipeline, f)
+	}
+}
+
+// item Build the menu item, with its sub menu
+func (m *filterMenu) item() *fyne.MenuItem {
+	m.items = []*fyne.MenuItem{fyne.NewMenuItem("None", func() {
+		m.SetFilter("")
+	})}
+	for _, name := range filter.Names {
+		m.items = append(m.items, fyne.NewMenuItem(name, func() {
+			m.SetFilter(name)
+		}))
+	}
+	m.ghostItem = fyne.NewMenuItem("Ghosting", func() {
+		m.SetGhosting(m.ghosting == nil)
+	})
+
+	items := append([]*fyne.MenuItem{}, m.items...)
+	items = append(items, fyne.NewMenuItemSeparator(), m.ghostItem)
+	m.refresh()
+	item := fyne.NewMenuItem("Filter", nil)
+	item.ChildMenu = fyne.NewMenu("", items...)
+	return item
+}
+
+func (m *filterMenu) refresh() {
+	m.mu.Lock()
+	name, ghosting := m.name, m.ghosting != nil
+	m.mu.Unlock()
+	for i, item := range m.items {
+		item.Checked = (i == 0 && name == "") || (i > 0 && filter.Names[i-1] == name)
+	}
+	if m.ghostItem != nil {
+		m.ghostItem.Checked = ghosting
+	}
+	if m.onMenu != nil {
+		m.onMenu()
+	}
+}
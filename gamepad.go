@@ -0,0 +1,56 @@
+//go:build !ci
+
+package main
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// gamepadDeadZone Stick deflection required to press a direction
+const gamepadDeadZone = 0.5
+
+// gamepadBindings Standard gamepad buttons bound to Game Boy buttons, using the Nintendo face buttons layout
+var gamepadBindings = map[glfw.GamepadButton]emulator.Button{
+	glfw.ButtonB:         emulator.ButtonA,
+	glfw.ButtonA:         emulator.ButtonB,
+	glfw.ButtonBack:      emulator.ButtonSelect,
+	glfw.ButtonStart:     emulator.ButtonStart,
+	glfw.ButtonDpadRight: emulator.ButtonRight,
+	glfw.ButtonDpadLeft:  emulator.ButtonLeft,
+	glfw.ButtonDpadUp:    emulator.ButtonUp,
+	glfw.ButtonDpadDown:  emulator.ButtonDown,
+}
+
+// pollGamepads Get the Game Boy buttons pressed on the joysticks exposed by GLFW.
+// Must be called from the main thread (through fyne.Do).
+func pollGamepads() emulator.Button {
+	var buttons emulator.Button
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		if !joy.Present() || !joy.IsGamepad() {
+			continue
+		}
+		state := joy.GetGamepadState()
+		if state == nil {
+			continue
+		}
+		for pad, button := range gamepadBindings {
+			if state.Buttons[pad] == glfw.Press {
+				buttons |= button
+			}
+		}
+
+		x, y := state.Axes[glfw.AxisLeftX], state.Axes[glfw.AxisLeftY]
+		if x > gamepadDeadZone {
+			buttons |= emulator.ButtonRight
+		} else if x < -gamepadDeadZone {
+			buttons |= emulator.ButtonLeft
+		}
+		if y > gamepadDeadZone {
+			buttons |= emulator.ButtonDown
+		} else if y < -gamepadDeadZone {
+			buttons |= emulator.ButtonUp
+		}
+	}
+	return buttons
+}
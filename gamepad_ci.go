@@ -0,0 +1,10 @@
+//go:build ci
+
+package main
+
+import "khopa.github.io/gogbemulator/emulator"
+
+// pollGamepads No joystick support with the software driver
+func pollGamepads() emulator.Button {
+	return 0
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+const windowTitle = "Go GB Emulator"
+
+// formatMemory Utility to print a memory section
+func formatMemory(mem []uint8, sp uint16) string {
+	var b strings.Builder
+	for i := 0; i < len(mem); i += 16 {
+		_, err := fmt.Fprintf(&b, "%04X: ", uint16(i)+sp)
+		if err != nil {
+			return ""
+		}
+		for j := 0; j < 16 && i+j < len(mem); j++ {
+			_, err := fmt.Fprintf(&b, "%02X ", mem[i+j])
+			if err != nil {
+				return ""
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// guiOptions Command line options of the window
+type guiOptions struct {
+	romPath        string // ROM loaded at start, if not empty
+	patchPath      string // Patch applied to the ROM loaded at start, the one named after it when empty
+	rewindInterval int
+	restorePalette bool            // Use the palette saved in preferences, false when given on the command line
+	speed          *emulator.Speed // Overrides the speed saved in preferences when set
+	turboSpeed     *emulator.Speed // Overrides the turbo speed saved in preferences when set
+}
+
+// runGUI Open the emulator window for dmg
+func runGUI(dmg *emulator.DMG, options guiOptions) {
+	// Create Fyne APP
+	a := app.NewWithID("io.github.khopa.gogbemulator")
+	prefs := a.Preferences()
+	w := a.NewWindow(windowTitle)
+
+	// --- Registers Left Panel ---
+	var updateMemory func()
+
+	screenImage := canvas.NewImageFromImage(dmg.Snapshot())
+	screen := newDisplay(w, prefs, screenImage)
+	screenContainer := container.NewBorder(widget.NewLabel("LCD"), nil, nil, nil)
+
+	regLabel := widget.NewLabel(formatRegisters(*dmg.Gbz80))
+
+	// Frames are filtered then handed over from the emulation goroutine to the UI one
+	var emu *runner
+	filters := newFilterMenu(prefs)
+	showFrame := func(frame image.Image, cpu emulator.Gbz80) {
+		frame = filters.Apply(frame)
+		fyne.Do(func() {
+			screenImage.Image = frame
+			screenImage.Refresh()
+			regLabel.SetText(formatRegisters(cpu))
+			emu.SetGamepad(pollGamepads())
+		})
+	}
+	emu = newRunner(dmg, options.rewindInterval, showFrame)
+	speeds := newSpeedMenu(prefs, emu, options.speed, options.turboSpeed)
+	movies := newMovieMenu(w, emu, func(dmg *emulator.DMG) {
+		showFrame(dmg.Snapshot(), *dmg.Gbz80)
+	})
+	states := newStateMenu(w, emu, func(dmg *emulator.DMG) {
+		showFrame(dmg.Snapshot(), *dmg.Gbz80)
+	}, func() {
+		updateMemory()
+	})
+	cheats := newCheatMenu(w, emu)
+	ramSearch := newRAMSearchPanel(a, emu, cheats)
+
+	// Breakpoints pause emulation, the one that fired being shown under the registers
+	breakLabel := widget.NewLabel("")
+	breakLabel.Wrapping = fyne.TextWrapWord
+	showBreak := func(hit *emulator.BreakHit) {
+		breakLabel.SetText("")
+		if hit != nil {
+			breakLabel.SetText(hit.String())
+		}
+	}
+
+	var runButton, pauseButton, stepButton, resetButton *widget.Button
+	setRunning := func(running bool) {
+		if running {
+			emu.Run()
+			runButton.Disable()
+			stepButton.Disable()
+			pauseButton.Enable()
+		} else {
+			emu.Pause()
+			runButton.Enable()
+			stepButton.Enable()
+			pauseButton.Disable()
+			updateMemory()
+		}
+	}
+	runButton = widget.NewButton("Run", func() {
+		showBreak(nil)
+		setRunning(true)
+	})
+	pauseButton = widget.NewButton("Pause", func() {
+		setRunning(false)
+	})
+	emu.onBreak = func(hit emulator.BreakHit) {
+		fyne.Do(func() {
+			setRunning(false)
+			showBreak(&hit)
+		})
+	}
+	stepButton = widget.NewButton("Step", func() {
+		var hit *emulator.BreakHit
+		emu.Do(func(dmg *emulator.DMG) {
+			dmg.Step()
+			hit = dmg.BreakHit()
+			showFrame(dmg.Snapshot(), *dmg.Gbz80)
+		})
+		showBreak(hit)
+		updateMemory()
+	})
+	resetButton = widget.NewButton("Reset", func() {
+		emu.Do(func(dmg *emulator.DMG) {
+			dmg.Reset()
+			showFrame(dmg.Snapshot(), *dmg.Gbz80)
+		})
+		updateMemory()
+	})
+	// Nothing to run until a ROM is loaded
+	runButton.Disable()
+	pauseButton.Disable()
+	stepButton.Disable()
+	resetButton.Disable()
+
+	regPanel := container.NewVBox(
+		widget.NewLabel("Registers"),
+		regLabel,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2, runButton, pauseButton, stepButton, resetButton),
+		breakLabel,
+	)
+
+	// --- Memory Viewer ---
+
+	memEntry := widget.NewMultiLineEntry()
+	memEntry.Wrapping = fyne.TextWrapOff
+	memEntry.SetText("Open a ROM from the File menu, or drop it on the window.")
+
+	memPanel := container.NewBorder(
+		widget.NewLabel("Memory"),
+		nil, nil, nil,
+		container.NewScroll(memEntry),
+	)
+
+	updateMemory = func() {
+		var dissasembly string
+		emu.Do(func(dmg *emulator.DMG) {
+			dissasembly = dmg.Disassemble(dmg.Gbz80.Pc, 15)
+		})
+		memEntry.SetText(dissasembly)
+	}
+
+	// --- Layout Split ---
+	topSplit := container.NewHSplit(
+		regPanel,
+		screenContainer,
+	)
+	topSplit.Offset = 0.25
+
+	mainLayout := container.NewVSplit(
+		topSplit,
+		memPanel,
+	)
+	mainLayout.Offset = 0.45
+
+	screen.SetFilterFactor(filters.Factor())
+	screen.setLayout(mainLayout, screenContainer)
+
+	// --- ROM loading ---
+	var refreshRecentROMs func()
+	var mainMenu *fyne.MainMenu
+	openROM := func(path string, patch string) {
+		readROM(w, path, patch, func(rom emulator.ROMFile) {
+			setRunning(false)
+			movies.Stop()
+			var err, cheatErr error
+			emu.Do(func(dmg *emulator.DMG) {
+				err = dmg.LoadROMFile(rom)
+				if err == nil {
+					dmg.Reset()
+					cheatErr = cheats.load(dmg)
+					filters.Reset()
+					showFrame(dmg.Snapshot(), *dmg.Gbz80)
+				}
+			})
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error loading ROM %s: %w", path, err), w)
+				return
+			}
+			if cheatErr != nil {
+				dialog.ShowError(fmt.Errorf("error loading cheats: %w", cheatErr), w)
+			}
+			addRecentROM(prefs, path)
+			refreshRecentROMs()
+			title := windowTitle + " - " + rom.Name
+			if rom.Patch != "" {
+				title += " + " + filepath.Base(rom.Patch)
+			}
+			w.SetTitle(title)
+			runButton.Enable()
+			stepButton.Enable()
+			resetButton.Enable()
+			states.SetEnabled(true)
+			movies.SetEnabled(true)
+			cheats.SetEnabled(true)
+			ramSearch.SetEnabled(true)
+			mainMenu.Refresh()
+			updateMemory()
+		})
+	}
+	loadROM := func(path string) {
+		openROM(path, "")
+	}
+
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		for _, uri := range uris {
+			if isROMFile(uri.Path()) {
+				loadROM(uri.Path())
+				return
+			}
+		}
+	})
+
+	// Keyboard : bound keys drive the joypad, hold Backspace to rewind, hold Tab for turbo, F11 toggles fullscreen
+	keyboard := &keyboardInput{
+		bindings: loadKeyBindings(prefs),
+		onChange: emu.SetKeys,
+	}
+	if deskCanvas, ok := w.Canvas().(desktop.Canvas); ok {
+		deskCanvas.SetOnKeyDown(func(e *fyne.KeyEvent) {
+			if keyboard.keyDown(e) {
+				return
+			}
+			switch {
+			case e.Name == fyne.KeyBackspace:
+				emu.SetRewinding(true)
+			case e.Name == turboKey:
+				emu.SetTurbo(true)
+			case e.Name == fyne.KeyF11:
+				screen.ToggleFullScreen()
+			case e.Name == fyne.KeyEscape && w.FullScreen():
+				screen.ToggleFullScreen()
+			}
+		})
+		deskCanvas.SetOnKeyUp(func(e *fyne.KeyEvent) {
+			if keyboard.keyUp(e) {
+				return
+			}
+			switch e.Name {
+			case fyne.KeyBackspace:
+				emu.SetRewinding(false)
+			case turboKey:
+				emu.SetTurbo(false)
+			}
+		})
+	}
+
+	// --- Menu ---
+	palettes := &paletteMenu{
+		window: w,
+		prefs:  prefs,
+		apply: func(s emulator.ColorScheme) {
+			emu.Do(func(dmg *emulator.DMG) {
+				dmg.SetColorScheme(s)
+				showFrame(dmg.Snapshot(), *dmg.Gbz80)
+			})
+		},
+	}
+	if options.restorePalette {
+		palettes.restore()
+	}
+	viewMenu := screen.menu()
+	viewMenu.Items = append(viewMenu.Items, fyne.NewMenuItemSeparator(), palettes.item(), filters.item())
+	openRecent := fyne.NewMenuItem("Open Recent", nil)
+	openRecent.ChildMenu = fyne.NewMenu("")
+	mainMenu = fyne.NewMainMenu(
+		fyne.NewMenu("File",
+			fyne.NewMenuItem("Open...", func() {
+				showOpenROMDialog(w, loadROM)
+			}),
+			openRecent,
+			fyne.NewMenuItemSeparator(),
+			states.save,
+			states.load,
+			fyne.NewMenuItemSeparator(),
+			movies.item(),
+			cheats.item(),
+		),
+		viewMenu,
+		speeds.menu(),
+		fyne.NewMenu("Tools",
+			ramSearch.item(),
+			fyne.NewMenuItem("Breakpoints...", func() {
+				showBreakpointsDialog(w, emu)
+			}),
+		),
+		fyne.NewMenu("Settings",
+			fyne.NewMenuItem("Controls...", func() {
+				showControlsDialog(w, keyboard, prefs)
+			}),
+		),
+	)
+	refreshRecentROMs = func() {
+		openRecent.ChildMenu.Items = recentROMsMenu(prefs, loadROM)
+		mainMenu.Refresh()
+	}
+	screen.onMenu = mainMenu.Refresh
+	palettes.onMenu = mainMenu.Refresh
+	filters.onMenu = mainMenu.Refresh
+	filters.onFactor = screen.SetFilterFactor
+	speeds.onMenu = mainMenu.Refresh
+	movies.onMenu = mainMenu.Refresh
+	cheats.onMenu = mainMenu.Refresh
+	ramSearch.onMenu = mainMenu.Refresh
+	refreshRecentROMs()
+	w.SetMainMenu(mainMenu)
+
+	if options.romPath != "" {
+		openROM(options.romPath, options.patchPath)
+	}
+
+	emu.Start()
+	w.ShowAndRun()
+}
+
+// formatRegisters Utility to print the CPU registers
+func formatRegisters(cpu emulator.Gbz80) string {
+	return fmt.Sprintf(
+		"AF: 0x%04X\nBC: 0x%04X\nDE: 0x%04X\nHL: 0x%04X\nSP: 0x%04X\nPC: 0x%04X",
+		cpu.Af,
+		cpu.Bc,
+		cpu.De,
+		cpu.Hl,
+		cpu.Sp,
+		cpu.Pc,
+	)
+}
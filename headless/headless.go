@@ -0,0 +1,233 @@
+// Package headless runs the emulator without display, for regression testing & scripting.
+// A run lasts a number of frames, or stops earlier when one of its stop conditions is met.
+package headless
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// ErrTimeout Returned when none of the stop conditions was met in the allowed frames
+var ErrTimeout = errors.New("timed out")
+
+// StopCondition Checked after every instruction, the run stops as soon as Check returns true
+type StopCondition struct {
+	Name  string
+	Check func(dmg *emulator.DMG) bool
+}
+
+// PCReached Stop when the program counter reaches pc
+func PCReached(pc uint16) StopCondition {
+	return StopCondition{
+		Name: fmt.Sprintf("PC reached 0x%04X", pc),
+		Check: func(dmg *emulator.DMG) bool {
+			return dmg.Gbz80.Pc == pc
+		},
+	}
+}
+
+// MemoryEquals Stop when the byte at address holds value
+func MemoryEquals(address uint16, value uint8) StopCondition {
+	return StopCondition{
+		Name: fmt.Sprintf("memory 0x%04X is 0x%02X", address, value),
+		Check: func(dmg *emulator.DMG) bool {
+			return dmg.Memory[address] == value
+		},
+	}
+}
+
+// SerialContains Stop when text has been sent on the serial port
+func SerialContains(text string) StopCondition {
+	return StopCondition{
+		Name: fmt.Sprintf("serial output contains %q", text),
+		Check: func(dmg *emulator.DMG) bool {
+			return bytes.Contains(dmg.SerialOutput(), []byte(text))
+		},
+	}
+}
+
+// InfiniteLoop Stop when the same instruction jumped to itself repeats times in a row (JR -2, JP to itself...),
+// which is how most test ROMs end
+func InfiniteLoop(repeats int) StopCondition {
+	var lastPC uint16
+	count := 0
+	return StopCondition{
+		Name: "infinite loop detected",
+		Check: func(dmg *emulator.DMG) bool {
+			if dmg.Gbz80.Pc == lastPC {
+				count++
+			} else {
+				lastPC = dmg.Gbz80.Pc
+				count = 0
+			}
+			return count >= repeats
+		},
+	}
+}
+
+// InputEvent Buttons pressed from Frame on, until the next event
+type InputEvent struct {
+	Frame   uint64
+	Buttons emulator.Button
+}
+
+// ParseInputScript Read an input script, one "<frame> <buttons>" event per line ("60 Start", "90 A+Right", "120 -").
+// Blank lines & lines starting with # are ignored.
+func ParseInputScript(r io.Reader) ([]InputEvent, error) {
+	var events []InputEvent
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected <frame> <buttons>", line)
+		}
+		frame, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid frame %q", line, fields[0])
+		}
+		var buttons emulator.Button
+		if len(fields) == 2 {
+			buttons, err = emulator.ParseButtons(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		events = append(events, InputEvent{Frame: frame, Buttons: buttons})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Frame < events[j].Frame
+	})
+	return events, nil
+}
+
+// Options Headless run configuration
+type Options struct {
+	Frames      int               // Frames to run, the run timing out after them when stop conditions are set
+	Stop        []StopCondition   // Any of them ends the run
+	Input       []InputEvent      // Scripted input, sorted by frame
+	Screenshots map[uint64]string // PNG files the LCD is saved to, by frame
+	Speed       emulator.Speed    // Pace frames at this speed, unlimited (the default) running them as fast as possible
+}
+
+// Result Outcome of a headless run
+type Result struct {
+	Frames   uint64             // Frames completed
+	Stopped  string             // Name of the stop condition met or breakpoint hit, empty when the run lasted all its frames
+	Break    *emulator.BreakHit // Breakpoint that stopped the run, if any
+	Duration time.Duration      // Wall clock time of the run
+}
+
+// FPS Emulated frames per second
+func (r Result) FPS() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Frames) / r.Duration.Seconds()
+}
+
+// Run Run dmg as configured by options.
+// ErrTimeout is returned when stop conditions are set and none was met, a CPU crash or a movie desync is reported as an error.
+// Breakpoints of dmg stop the run like stop conditions.
+func Run(dmg *emulator.DMG, options Options) (result Result, err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("crashed at frame %d, PC=0x%04X: %v", dmg.Frame, dmg.Gbz80.Pc, r)
+		}
+		result.Frames = dmg.Frame
+		result.Duration = time.Since(start)
+	}()
+
+	frameDuration := options.Speed.FrameDuration(dmg.Model)
+	first := dmg.Frame
+	end := dmg.Frame + uint64(options.Frames)
+	input := options.Input
+	frame := dmg.Frame
+	newFrame := true
+	for dmg.Frame < end {
+		if newFrame {
+			if err := dmg.MovieError(); err != nil {
+				return result, err
+			}
+			if frameDuration > 0 {
+				time.Sleep(time.Until(start.Add(frameDuration * time.Duration(dmg.Frame-first))))
+			}
+			if err := saveScreenshot(dmg, options.Screenshots); err != nil {
+				return result, err
+			}
+			for len(input) > 0 && input[0].Frame <= dmg.Frame {
+				dmg.SetJoypad(input[0].Buttons)
+				input = input[1:]
+			}
+		}
+
+		dmg.StepInstruction()
+
+		if hit := dmg.BreakHit(); hit != nil {
+			result.Stopped = hit.String()
+			result.Break = hit
+			return result, nil
+		}
+		for _, condition := range options.Stop {
+			if condition.Check(dmg) {
+				result.Stopped = condition.Name
+				return result, nil
+			}
+		}
+		newFrame = dmg.Frame != frame
+		frame = dmg.Frame
+	}
+
+	if err := dmg.MovieError(); err != nil {
+		return result, err
+	}
+	if err := saveScreenshot(dmg, options.Screenshots); err != nil {
+		return result, err
+	}
+	if len(options.Stop) > 0 {
+		return result, fmt.Errorf("%w after %d frames", ErrTimeout, options.Frames)
+	}
+	return result, nil
+}
+
+// saveScreenshot Save the LCD if a screenshot was requested for the current frame
+func saveScreenshot(dmg *emulator.DMG, screenshots map[uint64]string) error {
+	path, ok := screenshots[dmg.Frame]
+	if !ok {
+		return nil
+	}
+	return WritePNG(dmg, path)
+}
+
+// WritePNG Save the LCD of dmg as a PNG file
+func WritePNG(dmg *emulator.DMG, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, dmg.FrameBuffer()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
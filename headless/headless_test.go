@@ -0,0 +1,170 @@
+package headless
+
+import (
+	"errors"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// serialProgram Send "OK" on the serial port then loop forever
+var serialProgram = []uint8{
+	0x3E, 'O', // LD A, 'O'
+	0xE0, 0x01, // LDH (SB), A
+	0x3E, 0x81, // LD A, 0x81
+	0xE0, 0x02, // LDH (SC), A
+	0x3E, 'K',
+	0xE0, 0x01,
+	0x3E, 0x81,
+	0xE0, 0x02,
+	0x18, 0xFE, // JR -2
+}
+
+// makeTestDMG Create a DMG running program from the entry point
+func makeTestDMG(t *testing.T, program []uint8) *emulator.DMG {
+	t.Helper()
+	rom := make([]uint8, 0x8000)
+	copy(rom[emulator.CartridgeHeaderEntryPoint:], program)
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, rom, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dmg := emulator.MakeDMG()
+	if err := dmg.LoadROM(path); err != nil {
+		t.Fatal(err)
+	}
+	dmg.Reset()
+	return dmg
+}
+
+func TestRunUntilSerial(t *testing.T) {
+	dmg := makeTestDMG(t, serialProgram)
+	result, err := Run(dmg, Options{Frames: 10, Stop: []StopCondition{SerialContains("OK")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stopped != SerialContains("OK").Name {
+		t.Errorf("unexpected stop condition %q", result.Stopped)
+	}
+	if string(dmg.SerialOutput()) != "OK" {
+		t.Errorf("unexpected serial output %q", dmg.SerialOutput())
+	}
+	if dmg.Memory[emulator.InterruptFlagReg]&emulator.SerialInterruptBit == 0 {
+		t.Error("serial interrupt should be requested")
+	}
+}
+
+func TestRunUntilInfiniteLoop(t *testing.T) {
+	dmg := makeTestDMG(t, serialProgram)
+	result, err := Run(dmg, Options{Frames: 10, Stop: []StopCondition{InfiniteLoop(10)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stopped == "" || dmg.Gbz80.Pc != 0x110 {
+		t.Errorf("expected to stop on the final loop, stopped %q at PC=0x%04X", result.Stopped, dmg.Gbz80.Pc)
+	}
+}
+
+func TestRunUntilBreakpoint(t *testing.T) {
+	dmg := makeTestDMG(t, serialProgram)
+	b, _ := emulator.ParseBreakpoint("w 0xFF01=0x4B")
+	dmg.AddBreakpoint(b)
+	result, err := Run(dmg, Options{Frames: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Break == nil || result.Break.PC != 0x10A || result.Stopped != result.Break.String() {
+		t.Errorf("expected to stop on the write of 'K', stopped %q", result.Stopped)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	dmg := makeTestDMG(t, serialProgram)
+	result, err := Run(dmg, Options{Frames: 2, Stop: []StopCondition{PCReached(0x2000), MemoryEquals(0xC000, 0x42)}})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if result.Frames != 2 {
+		t.Errorf("expected 2 frames, got %d", result.Frames)
+	}
+}
+
+func TestRunSpeed(t *testing.T) {
+	dmg := makeTestDMG(t, serialProgram)
+	result, err := Run(dmg, Options{Frames: 3, Speed: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paced := 2 * emulator.Speed(4).FrameDuration(dmg.Model); result.Duration < paced {
+		t.Errorf("expected the run to be paced over %v, took %v", paced, result.Duration)
+	}
+	if result.FPS() <= 0 {
+		t.Errorf("unexpected FPS %v", result.FPS())
+	}
+}
+
+func TestRunInputAndScreenshots(t *testing.T) {
+	dmg := makeTestDMG(t, serialProgram)
+	dir := t.TempDir()
+	screenshots := map[uint64]string{
+		1: filepath.Join(dir, "1.png"),
+		3: filepath.Join(dir, "3.png"),
+	}
+	input := []InputEvent{{Frame: 1, Buttons: emulator.ButtonStart}, {Frame: 2, Buttons: emulator.ButtonA}}
+	if _, err := Run(dmg, Options{Frames: 3, Input: input, Screenshots: screenshots}); err != nil {
+		t.Fatal(err)
+	}
+	if dmg.Joypad() != emulator.ButtonA {
+		t.Errorf("expected A to be pressed, got 0x%02X", dmg.Joypad())
+	}
+	for _, path := range screenshots {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != emulator.ScreenWidth || img.Bounds().Dy() != emulator.ScreenHeight {
+			t.Errorf("%s: unexpected size %v", path, img.Bounds())
+		}
+	}
+}
+
+func TestParseInputScript(t *testing.T) {
+	script := `
+# Skip the title screen
+60 Start
+90 A+Right
+120 -
+30 B
+`
+	events, err := ParseInputScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []InputEvent{
+		{30, emulator.ButtonB},
+		{60, emulator.ButtonStart},
+		{90, emulator.ButtonA | emulator.ButtonRight},
+		{120, 0},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("event %d: expected %v, got %v", i, expected[i], events[i])
+		}
+	}
+
+	if _, err := ParseInputScript(strings.NewReader("60 Turbo")); err == nil {
+		t.Error("expected an error for an unknown button")
+	}
+}
@@ -0,0 +1,211 @@
+package headless
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// Test ROMs report their results in a few well known ways :
+//   - Blargg's tests print "Passed" or "Failed" on the serial port,
+//     and also write their status at $A000 once the DE B0 61 signature is at $A001-$A003, followed by a text at $A004.
+//     See : https://github.com/retrio/gb-test-roms
+//   - Mooneye's tests execute LD B,B once done, with B,C,D,E,H,L holding 3,5,8,13,21,34 on success, 0x42 on failure.
+//     See : https://github.com/Gekkio/mooneye-test-suite
+
+// TestStatus Outcome of a test ROM
+type TestStatus string
+
+const (
+	TestPassed  TestStatus = "PASS"
+	TestFailed  TestStatus = "FAIL"
+	TestTimeout TestStatus = "TIMEOUT"
+	TestCrashed TestStatus = "CRASH"
+)
+
+const (
+	blarggStatusAddress    = 0xA000
+	blarggSignatureAddress = 0xA001
+	blarggTextAddress      = 0xA004
+	blarggRunning          = 0x80
+	mooneyeBreakpoint      = 0x40 // LD B,B
+)
+
+var (
+	blarggSignature = []uint8{0xDE, 0xB0, 0x61}
+	mooneyePass     = []uint8{3, 5, 8, 13, 21, 34}
+)
+
+// TestResult Result of a single test ROM
+type TestResult struct {
+	ROM      string
+	Status   TestStatus
+	Message  string // Details reported by the ROM, or the error that stopped it
+	Frames   uint64
+	Duration time.Duration
+}
+
+// blarggSerialDone Has a Blargg test printed its result on the serial port, up to the end of the line
+func blarggSerialDone(dmg *emulator.DMG) bool {
+	out := dmg.SerialOutput()
+	for _, word := range []string{"Passed", "Failed"} {
+		if i := bytes.LastIndex(out, []byte(word)); i >= 0 && bytes.IndexByte(out[i:], '\n') >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// blarggMemoryDone Has a Blargg test written its final status at $A000
+func blarggMemoryDone(dmg *emulator.DMG) bool {
+	return bytes.Equal(dmg.Memory[blarggSignatureAddress:blarggTextAddress], blarggSignature) &&
+		dmg.Memory[blarggStatusAddress] != blarggRunning
+}
+
+// mooneyeDone Has a Mooneye test reached its LD B,B breakpoint
+func mooneyeDone(dmg *emulator.DMG) bool {
+	return dmg.LastOpcode == mooneyeBreakpoint
+}
+
+// RunTestROM Run the test ROM loaded in dmg for at most frames frames, and detect its result
+func RunTestROM(dmg *emulator.DMG, name string, frames int) TestResult {
+	start := time.Now()
+	result, err := Run(dmg, Options{
+		Frames: frames,
+		Stop: []StopCondition{
+			{Name: "blargg serial", Check: blarggSerialDone},
+			{Name: "blargg memory", Check: blarggMemoryDone},
+			{Name: "mooneye", Check: mooneyeDone},
+		},
+	})
+	test := TestResult{ROM: name, Frames: result.Frames, Duration: time.Since(start)}
+
+	switch {
+	case err != nil && result.Stopped == "":
+		test.Status = TestCrashed
+		test.Message = err.Error()
+		if errors.Is(err, ErrTimeout) {
+			test.Status = TestTimeout
+			test.Message = strings.TrimSpace(string(dmg.SerialOutput()))
+		}
+	case result.Stopped == "blargg serial":
+		test.Message = strings.TrimSpace(string(dmg.SerialOutput()))
+		test.Status = TestFailed
+		if bytes.Contains(dmg.SerialOutput(), []byte("Passed")) {
+			test.Status = TestPassed
+		}
+	case result.Stopped == "blargg memory":
+		test.Message = blarggText(dmg)
+		test.Status = TestFailed
+		if dmg.Memory[blarggStatusAddress] == 0 {
+			test.Status = TestPassed
+		} else if test.Message == "" {
+			test.Message = fmt.Sprintf("status 0x%02X", dmg.Memory[blarggStatusAddress])
+		}
+	case result.Stopped == "mooneye":
+		cpu := dmg.Gbz80
+		registers := []uint8{cpu.B(), cpu.C(), cpu.D(), cpu.E(), cpu.H(), cpu.L()}
+		test.Status = TestFailed
+		if bytes.Equal(registers, mooneyePass) {
+			test.Status = TestPassed
+		} else {
+			test.Message = fmt.Sprintf("B=%d C=%d D=%d E=%d H=%d L=%d", registers[0], registers[1], registers[2], registers[3], registers[4], registers[5])
+		}
+	}
+	return test
+}
+
+// blarggText Zero terminated text written by a Blargg test at $A004
+func blarggText(dmg *emulator.DMG) string {
+	text := dmg.Memory[blarggTextAddress:emulator.ExternalRAMEnd]
+	if end := bytes.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+	return strings.TrimSpace(string(text))
+}
+
+// WriteSummary Print results as a table, followed by the totals
+func WriteSummary(w io.Writer, results []TestResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROM\tRESULT\tFRAMES\tMESSAGE")
+	passed := 0
+	for _, r := range results {
+		if r.Status == TestPassed {
+			passed++
+		}
+		// Keep the table on one line per ROM
+		message := strings.Join(strings.Fields(r.Message), " ")
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", r.ROM, r.Status, r.Frames, message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d/%d passed\n", passed, len(results))
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit Write results as a JUnit XML report, failures & timeouts being reported as failures, crashes as errors
+func WriteJUnit(w io.Writer, suite string, results []TestResult) error {
+	s := junitTestSuite{Name: suite, Tests: len(results)}
+	for _, r := range results {
+		c := junitTestCase{Name: r.ROM, ClassName: suite, Time: r.Duration.Seconds(), SystemOut: r.Message}
+		problem := &junitProblem{Message: string(r.Status), Type: string(r.Status), Text: r.Message}
+		switch r.Status {
+		case TestFailed, TestTimeout:
+			c.Failure = problem
+			s.Failures++
+		case TestCrashed:
+			c.Error = problem
+			s.Errors++
+		}
+		s.Time += c.Time
+		s.Cases = append(s.Cases, c)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{s}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
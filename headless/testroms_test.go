@@ -0,0 +1,91 @@
+package headless
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// serialText Program sending text on the serial port
+func serialText(text string) []uint8 {
+	var program []uint8
+	for _, c := range []uint8(text) {
+		program = append(program, 0x3E, c, 0xE0, 0x01, 0x3E, 0x81, 0xE0, 0x02)
+	}
+	return program
+}
+
+// blarggMemory Program writing a Blargg result at $A000, the status being "running" until the text is written
+func blarggMemory(status uint8, text string) []uint8 {
+	program := []uint8{0x06, 0xA0, 0x0E, 0x00, 0x3E, 0x80, 0x02} // LD B, 0xA0 ; LD C, 0 ; LD A, 0x80 ; LD (BC), A
+	for i, b := range append([]uint8{0xDE, 0xB0, 0x61}, append([]uint8(text), 0)...) {
+		program = append(program, 0x0E, uint8(i+1), 0x3E, b, 0x02) // LD C, i+1 ; LD A, b ; LD (BC), A
+	}
+	return append(program, 0x0E, 0x00, 0x3E, status, 0x02)
+}
+
+// mooneyeResult Program setting B, C, D, E, H & L then reaching LD B,B
+func mooneyeResult(b, c, d, e, h, l uint8) []uint8 {
+	return []uint8{0x06, b, 0x0E, c, 0x16, d, 0x1E, e, 0x26, h, 0x2E, l, 0x40}
+}
+
+var loopForever = []uint8{0x18, 0xFE} // JR -2
+
+func TestRunTestROM(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		program []uint8
+		status  TestStatus
+		message string
+	}{
+		{"blargg serial pass", serialText("cpu_instrs\n\nPassed\n"), TestPassed, "Passed"},
+		{"blargg serial fail", serialText("Failed #2\n"), TestFailed, "Failed #2"},
+		{"blargg memory pass", blarggMemory(0x00, "Passed"), TestPassed, "Passed"},
+		{"blargg memory fail", blarggMemory(0x01, "Failed"), TestFailed, "Failed"},
+		{"mooneye pass", mooneyeResult(3, 5, 8, 13, 21, 34), TestPassed, ""},
+		{"mooneye fail", mooneyeResult(0x42, 0x42, 0x42, 0x42, 0x42, 0x42), TestFailed, "B=66"},
+		{"timeout", nil, TestTimeout, ""},
+	} {
+		dmg := makeTestDMG(t, append(tc.program, loopForever...))
+		result := RunTestROM(dmg, tc.name, 5)
+		if result.Status != tc.status {
+			t.Errorf("%s: expected %s, got %s (%s)", tc.name, tc.status, result.Status, result.Message)
+		}
+		if !strings.Contains(result.Message, tc.message) {
+			t.Errorf("%s: expected message to contain %q, got %q", tc.name, tc.message, result.Message)
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	results := []TestResult{
+		{ROM: "cpu_instrs.gb", Status: TestPassed, Frames: 10},
+		{ROM: "halt_bug.gb", Status: TestFailed, Message: "Failed\nhalt bug"},
+		{ROM: "boot_hwio.gb", Status: TestCrashed, Message: "overflow"},
+	}
+
+	var summary bytes.Buffer
+	if err := WriteSummary(&summary, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(summary.String(), "1/3 passed") || !strings.Contains(summary.String(), "Failed halt bug") {
+		t.Errorf("unexpected summary:\n%s", summary.String())
+	}
+
+	var report bytes.Buffer
+	if err := WriteJUnit(&report, "gb-test-roms", results); err != nil {
+		t.Fatal(err)
+	}
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(report.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	suite := parsed.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Errors != 1 {
+		t.Errorf("unexpected counts: %d tests, %d failures, %d errors", suite.Tests, suite.Failures, suite.Errors)
+	}
+	if suite.Cases[1].Failure == nil || suite.Cases[2].Error == nil || suite.Cases[0].Failure != nil {
+		t.Error("failures & errors not reported on the right cases")
+	}
+}
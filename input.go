@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// keyBindings Keyboard key bound to each Game Boy button
+type keyBindings map[emulator.Button]fyne.KeyName
+
+// bindingPreferencePrefix Bindings are persisted in the app preferences as "binding.<button>"
+const bindingPreferencePrefix = "binding."
+
+func defaultKeyBindings() keyBindings {
+	return keyBindings{
+		emulator.ButtonA:      fyne.KeyX,
+		emulator.ButtonB:      fyne.KeyZ,
+		emulator.ButtonSelect: desktop.KeyShiftRight,
+		emulator.ButtonStart:  fyne.KeyReturn,
+		emulator.ButtonRight:  fyne.KeyRight,
+		emulator.ButtonLeft:   fyne.KeyLeft,
+		emulator.ButtonUp:     fyne.KeyUp,
+		emulator.ButtonDown:   fyne.KeyDown,
+	}
+}
+
+// loadKeyBindings Read bindings from preferences, using defaults for the missing ones
+func loadKeyBindings(prefs fyne.Preferences) keyBindings {
+	bindings := defaultKeyBindings()
+	for button, key := range bindings {
+		bindings[button] = fyne.KeyName(prefs.StringWithFallback(bindingPreferencePrefix+button.String(), string(key)))
+	}
+	return bindings
+}
+
+// save Persist bindings in preferences
+func (kb keyBindings) save(prefs fyne.Preferences) {
+	for button, key := range kb {
+		prefs.SetString(bindingPreferencePrefix+button.String(), string(key))
+	}
+}
+
+// keyboardInput Track keys bound to Game Boy buttons
+type keyboardInput struct {
+	bindings keyBindings
+	pressed  emulator.Button
+	capture  func(key fyne.KeyName)        // When set, receives the next key press instead of the joypad
+	onChange func(pressed emulator.Button) // Called when the pressed buttons change
+}
+
+// keyDown Handle a key press, returns true if the key was used
+func (in *keyboardInput) keyDown(e *fyne.KeyEvent) bool {
+	if in.capture != nil {
+		capture := in.capture
+		in.capture = nil
+		capture(e.Name)
+		return true
+	}
+	for button, key := range in.bindings {
+		if key == e.Name {
+			in.pressed |= button
+			in.onChange(in.pressed)
+			return true
+		}
+	}
+	return false
+}
+
+// keyUp Handle a key release, returns true if the key was used
+func (in *keyboardInput) keyUp(e *fyne.KeyEvent) bool {
+	for button, key := range in.bindings {
+		if key == e.Name {
+			in.pressed &^= button
+			in.onChange(in.pressed)
+			return true
+		}
+	}
+	return false
+}
+
+// showControlsDialog Let the user rebind the keyboard keys, bindings are saved as soon as they change
+func showControlsDialog(w fyne.Window, in *keyboardInput, prefs fyne.Preferences) {
+	grid := container.NewGridWithColumns(2)
+	keyButtons := map[emulator.Button]*widget.Button{}
+	for _, button := range emulator.Buttons {
+		keyButton := widget.NewButton(string(in.bindings[button]), nil)
+		keyButton.OnTapped = func() {
+			keyButton.SetText("Press a key...")
+			in.capture = func(key fyne.KeyName) {
+				if key != fyne.KeyEscape {
+					in.bindings[button] = key
+					in.bindings.save(prefs)
+				}
+				keyButton.SetText(string(in.bindings[button]))
+			}
+		}
+		keyButtons[button] = keyButton
+		grid.Add(widget.NewLabel(button.String()))
+		grid.Add(keyButton)
+	}
+
+	defaults := widget.NewButton("Restore defaults", func() {
+		in.bindings = defaultKeyBindings()
+		in.bindings.save(prefs)
+		for button, keyButton := range keyButtons {
+			keyButton.SetText(string(in.bindings[button]))
+		}
+	})
+
+	d := dialog.NewCustom("Controls", "Close", container.NewVBox(grid, defaults), w)
+	d.SetOnClosed(func() {
+		in.capture = nil
+	})
+	d.Show()
+}
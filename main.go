@@ -1,142 +1,146 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"os"
 	"strings"
-	"time"
+)
 
-	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/widget"
-	"khopa.github.io/gogbemulator/emulator"
+// Exit codes, shared by every command so that scripts can tell failures apart
+const (
+	exitOK      = 0 // Success
+	exitFailure = 1 // A test failed or a run timed out
+	exitUsage   = 2 // Invalid command line
+	exitError   = 3 // A file could not be read or written
 )
 
-// formatMemory Utility to print a memory section
-func formatMemory(mem []uint8, sp uint16) string {
-	var b strings.Builder
-	for i := 0; i < len(mem); i += 16 {
-		_, err := fmt.Fprintf(&b, "%04X: ", uint16(i)+sp)
-		if err != nil {
-			return ""
-		}
-		for j := 0; j < 16 && i+j < len(mem); j++ {
-			_, err := fmt.Fprintf(&b, "%02X ", mem[i+j])
-			if err != nil {
-				return ""
-			}
-		}
-		b.WriteString("\n")
-	}
-	return b.String()
+// command CLI subcommand
+type command struct {
+	name    string
+	usage   string // Arguments, after the command name
+	summary string
+	run     func(name string, args []string) int
 }
 
-func main() {
+var commands []command
 
-	// Create emulator and load initial rom
-	dmg := emulator.MakeDMG()
-	dmg.Print()
-	err := dmg.LoadROM("testrom.gb")
-	if err != nil {
-		fmt.Printf("Error loading ROM: %v\n, please add your rom with name 'testrom.gb'"+
-			"in the working dir, it is not included by default in repo.", err)
-		return
+func init() {
+	commands = []command{
+		{"run", "[options] [rom]", "Open the emulator window, optionally loading rom", runCommand},
+		{"headless", "[options] <rom>", "Run rom without display, optionally saving a screenshot", headlessCommand},
+		{"disasm", "[options] <rom>", "Disassemble rom", disasmCommand},
+		{"info", "<rom>", "Print the cartridge header of rom", infoCommand},
+		{"test", "[options] <dir>", "Run every Blargg or Mooneye test ROM found in dir, reporting pass/fail", testCommand},
+		{"help", "[command]", "Print help about a command", helpCommand},
 	}
-	dmg.Gbz80.Pc = 0x150
-
-	dissasembly := emulator.Disassembly("testrom.gb", dmg.Gbz80.Pc, 20)
-
-	// Create Fyne APP
-	a := app.New()
-	w := a.NewWindow("Go GB Emulator")
-
-	// --- Registers Left Panel ---
-	var updateRegisters func()
-	var updateMemory func()
-
-	screenImage := canvas.NewImageFromImage(dmg.Snapshot())
-	screenImage.ScaleMode = canvas.ImageScalePixels
-	screenImage.FillMode = canvas.ImageFillContain
-	screenContainer := container.NewVBox(
-		widget.NewLabel("LCD"),
-		screenImage,
-	)
-	screenImage.SetMinSize(fyne.NewSize(
-		160*3,
-		144*3,
-	))
-
-	stepButton := widget.NewButton("Step", func() {
-		dmg.Step()
-		updateRegisters()
-		updateMemory()
-		screenImage.Image = dmg.Snapshot()
-		screenImage.Refresh()
-	})
+}
 
-	regLabel := widget.NewLabel("")
-	regPanel := container.NewVBox(
-		widget.NewLabel("Registers"),
-		regLabel,
-		widget.NewSeparator(),
-		stepButton,
-	)
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
+}
 
-	updateRegisters = func() {
-		regLabel.SetText(fmt.Sprintf(
-			"AF: 0x%04X\nBC: 0x%04X\nDE: 0x%04X\nHL: 0x%04X\nSP: 0x%04X\nPC: 0x%04X",
-			dmg.Gbz80.Af,
-			dmg.Gbz80.Bc,
-			dmg.Gbz80.De,
-			dmg.Gbz80.Hl,
-			dmg.Gbz80.Sp,
-			dmg.Gbz80.Pc,
-		))
+// runCLI Run the command given by args, returning the exit code.
+// Without command the emulator window is opened, so that double clicking or dropping a ROM on the binary still works.
+func runCLI(args []string) int {
+	switch {
+	case len(args) == 0:
+		return runCommand("run", nil)
+	case args[0] == "-h" || args[0] == "--help":
+		printUsage()
+		return exitOK
+	case strings.HasPrefix(args[0], "-"):
+		return runCommand("run", args)
 	}
+	if cmd, ok := findCommand(args[0]); ok {
+		return cmd.run(cmd.name, args[1:])
+	}
+	if _, err := os.Stat(args[0]); err == nil {
+		return runCommand("run", args)
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage()
+	return exitUsage
+}
 
-	// --- Memory Viewer ---
-
-	memEntry := widget.NewMultiLineEntry()
-	memEntry.Wrapping = fyne.TextWrapOff
-	memEntry.SetText(dissasembly)
-
-	memPanel := container.NewBorder(
-		widget.NewLabel("Memory"),
-		nil, nil, nil,
-		container.NewScroll(memEntry),
-	)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
 
-	updateMemory = func() {
-		dissasembly = emulator.Disassembly("testrom.gb", dmg.Gbz80.Pc, 15)
-		memEntry.SetText(dissasembly)
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s help <command>' for the options of a command.\n", os.Args[0])
+}
 
-	// --- Layout Split ---
-	topSplit := container.NewHSplit(
-		regPanel,
-		screenContainer,
-	)
-	topSplit.Offset = 0.25
+func helpCommand(_ string, args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitOK
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return exitUsage
+	}
+	return cmd.run(cmd.name, []string{"--help"})
+}
 
-	mainLayout := container.NewVSplit(
-		topSplit,
-		memPanel,
-	)
-	mainLayout.Offset = 0.45
+// newFlagSet Create the flag set of a command, printing its usage line on --help
+func newFlagSet(name string) *flag.FlagSet {
+	cmd, _ := findCommand(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s %s\n\n%s\n", os.Args[0], cmd.name, cmd.usage, cmd.summary)
+		if hasFlags(fs) {
+			fmt.Fprintf(fs.Output(), "\nOptions:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
 
-	w.SetContent(mainLayout)
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
 
-	// simulate emulator updates
-	go func() {
-		for {
-			time.Sleep(500 * time.Millisecond)
-			// run
-			// dmg.Step()
-			fyne.Do(updateRegisters)
-			//fyne.Do(updateMemory)
+// parseArgs Parse args, allowing flags after the positional arguments (rom --frames 10).
+// Exactly min to max positional arguments are expected. On failure, the returned code is the one the command should exit with.
+func parseArgs(fs *flag.FlagSet, args []string, min int, max int) ([]string, int, bool) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, exitOK, false
+			}
+			return nil, exitUsage, false
 		}
-	}()
-
-	w.ShowAndRun()
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < min || len(positional) > max {
+		fmt.Fprintf(fs.Output(), "%s: expected ", fs.Name())
+		if min == max {
+			fmt.Fprintf(fs.Output(), "%d argument(s), got %d\n", min, len(positional))
+		} else {
+			fmt.Fprintf(fs.Output(), "%d to %d arguments, got %d\n", min, max, len(positional))
+		}
+		fs.Usage()
+		return nil, exitUsage, false
+	}
+	return positional, exitOK, true
 }
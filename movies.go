@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// movieExtension Extension of movie files
+const movieExtension = ".gbm"
+
+// movieExtensions Movie files that can be played back : native ones, then the imported BizHawk & VisualBoyAdvance ones
+var movieExtensions = []string{movieExtension, ".bk2", ".vbm"}
+
+// movieMenu "Movie" menu : record the input from power on or from the current state, play movies back.
+// Movies played back are verified, a desync stopping playback with an error.
+type movieMenu struct {
+	window     fyne.Window
+	emu        *runner
+	show       func(dmg *emulator.DMG) // Show the frame once a movie starts, called with exclusive access to the DMG
+	path       string                  // File the movie being recorded is saved to when stopped
+	record     *fyne.MenuItem
+	recordHere *fyne.MenuItem
+	play       *fyne.MenuItem
+	stop       *fyne.MenuItem
+	onMenu     func() // Called when menu items need a refresh
+}
+
+// newMovieMenu Create the menu, its items being disabled until a ROM is loaded
+func newMovieMenu(w fyne.Window, emu *runner, show func(dmg *emulator.DMG)) *movieMenu {
+	m := &movieMenu{window: w, emu: emu, show: show}
+	m.record = fyne.NewMenuItem("Record From Power On...", func() {
+		m.showRecordDialog(false)
+	})
+	m.recordHere = fyne.NewMenuItem("Record From Here...", func() {
+		m.showRecordDialog(true)
+	})
+	m.play = fyne.NewMenuItem("Play...", m.showPlayDialog)
+	m.stop = fyne.NewMenuItem("Stop", m.Stop)
+	emu.Do(func(dmg *emulator.DMG) {
+		dmg.OnMovieEnd = func(err error) {
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("movie playback stopped: %w", err), w)
+				}
+				m.SetEnabled(true)
+			})
+		}
+	})
+	m.SetEnabled(false)
+	return m
+}
+
+// SetEnabled Enable starting movies, once a ROM is loaded
+func (m *movieMenu) SetEnabled(enabled bool) {
+	var active bool
+	m.emu.Do(func(dmg *emulator.DMG) {
+		active = dmg.MovieRecording() || dmg.MoviePlaying()
+	})
+	m.record.Disabled = !enabled || active
+	m.recordHere.Disabled = !enabled || active
+	m.play.Disabled = !enabled || active
+	m.stop.Disabled = !active
+	if m.onMenu != nil {
+		m.onMenu()
+	}
+}
+
+// item Build the menu item, with its sub menu
+func (m *movieMenu) item() *fyne.MenuItem {
+	item := fyne.NewMenuItem("Movie", nil)
+	item.ChildMenu = fyne.NewMenu("", m.record, m.recordHere, m.play, fyne.NewMenuItemSeparator(), m.stop)
+	return item
+}
+
+// Stop Stop the movie if any, saving it when recording
+func (m *movieMenu) Stop() {
+	var recorded *emulator.Movie
+	active := false
+	m.emu.Do(func(dmg *emulator.DMG) {
+		active = dmg.MovieRecording() || dmg.MoviePlaying()
+		recorded = dmg.StopMovie()
+	})
+	if !active {
+		return
+	}
+	if recorded != nil {
+		if err := writeMovieFile(recorded, m.path); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving movie %s: %w", m.path, err), m.window)
+		}
+	}
+	m.SetEnabled(true)
+}
+
+// showRecordDialog Ask for the movie file, then start recording
+func (m *movieMenu) showRecordDialog(fromState bool) {
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		m.path = writer.URI().Path()
+		_ = writer.Close()
+		m.emu.Do(func(dmg *emulator.DMG) {
+			err = dmg.RecordMovie(fromState)
+			m.show(dmg)
+		})
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error recording movie: %w", err), m.window)
+		}
+		m.SetEnabled(true)
+	}, m.window)
+	d.SetFileName("movie" + movieExtension)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{movieExtension}))
+	d.Show()
+}
+
+// showPlayDialog Ask for a movie file, then play it back
+func (m *movieMenu) showPlayDialog() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		path := reader.URI().Path()
+		_ = reader.Close()
+		m.emu.Do(func(dmg *emulator.DMG) {
+			var movie *emulator.Movie
+			movie, err = readMovieFile(path, dmg.ROM)
+			if err == nil {
+				err = dmg.PlayMovie(movie, true)
+				m.show(dmg)
+			}
+		})
+		if err != nil {
+			if errors.Is(err, emulator.ErrMovieROMMismatch) {
+				err = fmt.Errorf("%w, load the ROM it was recorded with first", err)
+			}
+			dialog.ShowError(fmt.Errorf("error playing movie %s: %w", path, err), m.window)
+		}
+		m.SetEnabled(true)
+	}, m.window)
+	d.SetFilter(storage.NewExtensionFileFilter(movieExtensions))
+	d.Show()
+}
+
+// writeMovieFile Save m to path
+func writeMovieFile(m *emulator.Movie, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readMovieFile Read the movie saved at path, BK2 & VBM movies being imported for rom
+func readMovieFile(path string, rom []uint8) (*emulator.Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".bk2":
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return emulator.ReadBK2(f, info.Size(), rom)
+	case ".vbm":
+		return emulator.ReadVBM(f, rom)
+	}
+	return emulator.ReadMovie(f)
+}
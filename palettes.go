@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// palettePreference Color scheme in use : a built-in scheme name, or the path of a scheme file
+const palettePreference = "display.palette"
+
+// resolveColorScheme Get the built-in scheme named value, or load the scheme file at value
+func resolveColorScheme(value string) (emulator.ColorScheme, error) {
+	if s, ok := emulator.FindColorScheme(value); ok {
+		return s, nil
+	}
+	return emulator.LoadColorScheme(value)
+}
+
+// paletteMenu "Palette" menu : built-in schemes, then loading a scheme file.
+// apply is called with the chosen scheme, which is saved in preferences.
+type paletteMenu struct {
+	window  fyne.Window
+	prefs   fyne.Preferences
+	apply   func(s emulator.ColorScheme)
+	items   []*fyne.MenuItem
+	current string
+	onMenu  func() // Called when menu items need a refresh
+}
+
+// item Build the menu item, with its sub menu
+func (m *paletteMenu) item() *fyne.MenuItem {
+	m.current = m.prefs.String(palettePreference)
+	m.items = nil
+	for _, s := range emulator.ColorSchemes {
+		m.items = append(m.items, fyne.NewMenuItem(s.Name, func() {
+			m.choose(s.Name, s)
+		}))
+	}
+	load := fyne.NewMenuItem("Load Palette File...", func() {
+		d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			_ = reader.Close()
+			s, err := emulator.LoadColorScheme(path)
+			if err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+			m.choose(path, s)
+		}, m.window)
+		d.Show()
+	})
+
+	items := append([]*fyne.MenuItem{}, m.items...)
+	items = append(items, fyne.NewMenuItemSeparator(), load)
+	m.refresh()
+	palette := fyne.NewMenuItem("Palette", nil)
+	palette.ChildMenu = fyne.NewMenu("", items...)
+	return palette
+}
+
+// restore Apply the scheme saved in preferences, if any
+func (m *paletteMenu) restore() {
+	value := m.prefs.String(palettePreference)
+	if value == "" {
+		return
+	}
+	s, err := resolveColorScheme(value)
+	if err != nil {
+		// Scheme file moved or deleted since
+		m.prefs.RemoveValue(palettePreference)
+		return
+	}
+	m.apply(s)
+}
+
+func (m *paletteMenu) choose(value string, s emulator.ColorScheme) {
+	m.prefs.SetString(palettePreference, value)
+	m.current = value
+	m.apply(s)
+	m.refresh()
+}
+
+func (m *paletteMenu) refresh() {
+	for i, item := range m.items {
+		item.Checked = emulator.ColorSchemes[i].Name == m.current
+	}
+	if m.onMenu != nil {
+		m.onMenu()
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"khopa.github.io/gogbemulator/emulator"
+	"khopa.github.io/gogbemulator/ramsearch"
+)
+
+// maxRAMSearchResults Candidates listed at most, the search going on with all of them
+const maxRAMSearchResults = 1000
+
+// ramSearchPanel "RAM Search..." window : snapshot RAM then filter candidates while the game runs,
+// turning the variable found into a GameShark cheat
+type ramSearchPanel struct {
+	app    fyne.App
+	emu    *runner
+	cheats *cheatMenu
+	window fyne.Window // Open panel, nil when closed
+	search *ramsearch.Search
+	open   *fyne.MenuItem
+	onMenu func() // Called when menu items need a refresh
+}
+
+// newRAMSearchPanel Create the panel, its menu item being disabled until a ROM is loaded
+func newRAMSearchPanel(a fyne.App, emu *runner, cheats *cheatMenu) *ramSearchPanel {
+	p := &ramSearchPanel{app: a, emu: emu, cheats: cheats}
+	p.open = fyne.NewMenuItem("RAM Search...", p.show)
+	p.open.Disabled = true
+	return p
+}
+
+// item Get the menu item
+func (p *ramSearchPanel) item() *fyne.MenuItem {
+	return p.open
+}
+
+// SetEnabled Enable searching, once a ROM is loaded. A new ROM discards the current search.
+func (p *ramSearchPanel) SetEnabled(enabled bool) {
+	p.open.Disabled = !enabled
+	p.search = nil
+	if p.window != nil {
+		p.window.Close()
+	}
+	if p.onMenu != nil {
+		p.onMenu()
+	}
+}
+
+// snapshot Copy the memory of the emulated machine
+func (p *ramSearchPanel) snapshot() []uint8 {
+	var memory []uint8
+	p.emu.Do(func(dmg *emulator.DMG) {
+		memory = append([]uint8(nil), dmg.Memory[:]...)
+	})
+	return memory
+}
+
+// show Open the panel, or bring it to front
+func (p *ramSearchPanel) show() {
+	if p.window != nil {
+		p.window.RequestFocus()
+		return
+	}
+	w := p.app.NewWindow("RAM Search")
+	p.window = w
+	w.SetOnClosed(func() {
+		p.window = nil
+	})
+
+	regions := map[string]ramsearch.Region{}
+	var regionNames []string
+	for _, r := range ramsearch.Regions {
+		regions[r.Name] = r
+		regionNames = append(regionNames, r.Name)
+	}
+	regionChecks := widget.NewCheckGroup(regionNames, nil)
+	regionChecks.Horizontal = true
+	regionChecks.SetSelected(regionNames)
+	size := widget.NewSelect([]string{"8-bit", "16-bit"}, nil)
+	size.SetSelected("8-bit")
+	bcd := widget.NewCheck("BCD", nil)
+	comparison := widget.NewSelect(ramsearch.Comparisons, nil)
+	comparison.SetSelected(ramsearch.Equal.String())
+	value := widget.NewEntry()
+	value.SetPlaceHolder("Value (42, 0x2A)")
+	status := widget.NewLabel("Start a new search to snapshot RAM.")
+
+	var candidates []ramsearch.Candidate
+	selected := -1
+	results := widget.NewList(
+		func() int {
+			return len(candidates)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("0xFFFF: 65535 (0xFFFF), was 65535 (0xFFFF)")
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			c := candidates[id]
+			o.(*widget.Label).SetText(fmt.Sprintf("0x%04X: %s, was %s", c.Address, p.search.Format(c.Value), p.search.Format(c.Previous)))
+		},
+	)
+	results.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	refresh := func() {
+		candidates = nil
+		selected = -1
+		results.UnselectAll()
+		if p.search != nil {
+			candidates = p.search.Candidates()
+			status.SetText(fmt.Sprintf("%d candidates", len(candidates)))
+			if len(candidates) > maxRAMSearchResults {
+				candidates = candidates[:maxRAMSearchResults]
+				status.SetText(fmt.Sprintf("%d candidates, %d first ones listed", p.search.Len(), maxRAMSearchResults))
+			}
+		}
+		results.Refresh()
+	}
+
+	newSearch := widget.NewButton("New Search", func() {
+		options := ramsearch.Options{Size: ramsearch.Size8, BCD: bcd.Checked}
+		if size.Selected == "16-bit" {
+			options.Size = ramsearch.Size16
+		}
+		for _, name := range regionChecks.Selected {
+			options.Regions = append(options.Regions, regions[name])
+		}
+		search, err := ramsearch.New(p.snapshot(), options)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		p.search = search
+		refresh()
+	})
+	filter := widget.NewButton("Filter", func() {
+		if p.search == nil {
+			return
+		}
+		cmp := ramsearch.Equal
+		for i, name := range ramsearch.Comparisons {
+			if name == comparison.Selected {
+				cmp = ramsearch.Comparison(i)
+			}
+		}
+		var v int64
+		if cmp == ramsearch.Value {
+			var err error
+			if v, err = strconv.ParseInt(value.Text, 0, 32); err != nil {
+				dialog.ShowError(fmt.Errorf("invalid value %q", value.Text), w)
+				return
+			}
+		}
+		p.search.Filter(p.snapshot(), cmp, int(v))
+		refresh()
+	})
+	addCheat := widget.NewButton("Add Cheat", func() {
+		if selected < 0 || selected >= len(candidates) {
+			return
+		}
+		p.cheats.add(p.freezeCheats(candidates[selected])...)
+	})
+
+	controls := container.NewVBox(
+		regionChecks,
+		container.NewHBox(size, bcd, newSearch),
+		container.NewBorder(nil, nil, comparison, filter, value),
+		status,
+	)
+	w.SetContent(container.NewBorder(controls, addCheat, nil, nil, results))
+	w.Resize(fyne.NewSize(420, 480))
+	refresh()
+	w.Show()
+}
+
+// freezeCheats GameShark codes holding the candidate to its current value
+func (p *ramSearchPanel) freezeCheats(c ramsearch.Candidate) []emulator.Cheat {
+	memory := p.snapshot()
+	var cheats []emulator.Cheat
+	for i := range int(p.search.Options().Size) {
+		address := c.Address + uint16(i)
+		code := fmt.Sprintf("01%02X%02X%02X", memory[address], address&0xFF, address>>8)
+		cheat, err := emulator.ParseCheat(code)
+		if err != nil {
+			continue
+		}
+		cheat.Name = fmt.Sprintf("RAM 0x%04X", address)
+		cheat.Enabled = true
+		cheats = append(cheats, cheat)
+	}
+	return cheats
+}
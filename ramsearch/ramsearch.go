@@ -0,0 +1,168 @@
+// Package ramsearch locates game variables in RAM : memory is snapshot, then candidates are narrowed down
+// by comparing their value with the previous snapshot (equal, changed, increased, decreased) or with a specific value,
+// until only the address of the variable is left.
+package ramsearch
+
+import (
+	"fmt"
+)
+
+// Region Memory area searched, from Start to End excluded
+type Region struct {
+	Name       string
+	Start, End int
+}
+
+var (
+	CartRAM = Region{"Cartridge RAM", 0xA000, 0xC000}
+	WRAM    = Region{"WRAM", 0xC000, 0xE000}
+	HRAM    = Region{"HRAM", 0xFF80, 0xFFFF}
+)
+
+// Regions Regions that can be searched, by address
+var Regions = []Region{CartRAM, WRAM, HRAM}
+
+// Size Number of bytes of the searched values
+type Size int
+
+const (
+	Size8  Size = 1
+	Size16 Size = 2 // Little endian
+)
+
+// Comparison Filter applied to candidates
+type Comparison int
+
+const (
+	Equal     Comparison = iota // Same value as in the previous snapshot
+	Changed                     // Different value than in the previous snapshot
+	Increased                   // Greater value than in the previous snapshot
+	Decreased                   // Lower value than in the previous snapshot
+	Value                       // Specific value
+)
+
+// Comparisons Comparisons offered to users, by name
+var Comparisons = []string{"Equal", "Changed", "Increased", "Decreased", "Value"}
+
+// String Get the name of c
+func (c Comparison) String() string {
+	if int(c) < len(Comparisons) {
+		return Comparisons[c]
+	}
+	return fmt.Sprintf("Comparison(%d)", int(c))
+}
+
+// Options What is searched
+type Options struct {
+	Regions []Region
+	Size    Size
+	BCD     bool // Values are binary coded decimals (0x42 is 42), values with non decimal digits never match
+}
+
+// Candidate Address still matching every filter
+type Candidate struct {
+	Address  uint16
+	Value    int // -1 for invalid BCD
+	Previous int // Value in the previous snapshot
+}
+
+// Search Candidates left by the filters applied so far
+type Search struct {
+	options    Options
+	candidates []Candidate
+}
+
+// New Start a search from a snapshot of memory (64KB), every address of the regions being a candidate
+func New(memory []uint8, options Options) (*Search, error) {
+	if options.Size != Size8 && options.Size != Size16 {
+		return nil, fmt.Errorf("invalid value size %d", options.Size)
+	}
+	if len(options.Regions) == 0 {
+		return nil, fmt.Errorf("no region to search")
+	}
+	s := &Search{options: options}
+	for _, r := range options.Regions {
+		for address := r.Start; address+int(options.Size) <= r.End; address++ {
+			v := s.read(memory, uint16(address))
+			s.candidates = append(s.candidates, Candidate{Address: uint16(address), Value: v, Previous: v})
+		}
+	}
+	return s, nil
+}
+
+// Options Get what is searched
+func (s *Search) Options() Options {
+	return s.options
+}
+
+// Candidates Get the candidates left, by address
+func (s *Search) Candidates() []Candidate {
+	return s.candidates
+}
+
+// Len Get the number of candidates left
+func (s *Search) Len() int {
+	return len(s.candidates)
+}
+
+// Filter Take a new snapshot of memory and keep the candidates matching cmp, value only being used by the Value comparison.
+// Returns the number of candidates left.
+func (s *Search) Filter(memory []uint8, cmp Comparison, value int) int {
+	kept := s.candidates[:0]
+	for _, c := range s.candidates {
+		current := s.read(memory, c.Address)
+		if current < 0 || !matches(cmp, current, c.Value, value) {
+			continue
+		}
+		kept = append(kept, Candidate{Address: c.Address, Value: current, Previous: c.Value})
+	}
+	s.candidates = kept
+	return len(kept)
+}
+
+// matches Does current match cmp, previous being the value in the previous snapshot
+func matches(cmp Comparison, current, previous, value int) bool {
+	switch cmp {
+	case Equal:
+		return current == previous
+	case Changed:
+		return current != previous
+	case Increased:
+		return previous >= 0 && current > previous
+	case Decreased:
+		return previous >= 0 && current < previous
+	case Value:
+		return current == value
+	}
+	return false
+}
+
+// read Decode the value at address, -1 for invalid BCD
+func (s *Search) read(memory []uint8, address uint16) int {
+	var v int
+	for i := int(s.options.Size) - 1; i >= 0; i-- {
+		b := int(memory[int(address)+i])
+		if !s.options.BCD {
+			v = v<<8 | b
+			continue
+		}
+		if b>>4 > 9 || b&0x0F > 9 {
+			return -1
+		}
+		v = v*100 + (b>>4)*10 + b&0x0F
+	}
+	return v
+}
+
+// Format Format a candidate value for display
+func (s *Search) Format(v int) string {
+	switch {
+	case v < 0:
+		return "-"
+	case s.options.BCD:
+		return fmt.Sprintf("%d", v)
+	case s.options.Size == Size16:
+		return fmt.Sprintf("%d (0x%04X)", v, v)
+	}
+	return fmt.Sprintf("%d (0x%02X)", v, v)
+}
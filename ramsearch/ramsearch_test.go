@@ -0,0 +1,71 @@
+package ramsearch
+
+import (
+	"testing"
+)
+
+func addresses(s *Search) []uint16 {
+	var a []uint16
+	for _, c := range s.Candidates() {
+		a = append(a, c.Address)
+	}
+	return a
+}
+
+func TestSearch8(t *testing.T) {
+	memory := make([]uint8, 0x10000)
+	memory[0xC010] = 3 // Lives
+	memory[0xFF90] = 3
+	s, err := New(memory, Options{Regions: []Region{WRAM, HRAM}, Size: Size8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 0x2000+0x7F {
+		t.Fatalf("expected every address to be a candidate, got %d", s.Len())
+	}
+	if n := s.Filter(memory, Value, 3); n != 2 {
+		t.Fatalf("expected 2 candidates holding 3, got %d", n)
+	}
+	memory[0xC010] = 2
+	memory[0xFF90] = 4
+	s.Filter(memory, Decreased, 0)
+	if a := addresses(s); len(a) != 1 || a[0] != 0xC010 {
+		t.Fatalf("expected the decreased address only, got %v", a)
+	}
+	if c := s.Candidates()[0]; c.Value != 2 || c.Previous != 3 {
+		t.Errorf("unexpected candidate %+v", c)
+	}
+	if s.Filter(memory, Changed, 0) != 0 {
+		t.Error("expected no candidate to change")
+	}
+}
+
+func TestSearch16AndBCD(t *testing.T) {
+	memory := make([]uint8, 0x10000)
+	memory[0xA100], memory[0xA101] = 0x34, 0x12 // Little endian 0x1234
+	s, _ := New(memory, Options{Regions: []Region{CartRAM}, Size: Size16})
+	if s.Len() != 0x2000-1 {
+		t.Errorf("16-bit values shouldn't overflow the region, got %d candidates", s.Len())
+	}
+	s.Filter(memory, Value, 0x1234)
+	if a := addresses(s); len(a) != 1 || a[0] != 0xA100 {
+		t.Fatalf("expected 0xA100, got %v", a)
+	}
+
+	memory[0xC000], memory[0xC001] = 0x99, 0x01 // Score 199
+	s, _ = New(memory, Options{Regions: []Region{WRAM}, Size: Size16, BCD: true})
+	memory[0xC000], memory[0xC001] = 0x00, 0x02 // Score 200
+	s.Filter(memory, Increased, 0)
+	s.Filter(memory, Value, 200)
+	if a := addresses(s); len(a) != 1 || a[0] != 0xC000 {
+		t.Fatalf("expected 0xC000, got %v", a)
+	}
+
+	memory[0xC000] = 0x0A
+	if s.Filter(memory, Equal, 0) != 0 {
+		t.Error("invalid BCD values shouldn't match")
+	}
+	if _, err := New(memory, Options{Regions: []Region{WRAM}, Size: 4}); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+const (
+	recentROMsPreference = "recentROMs"
+	maxRecentROMs        = 10
+)
+
+// romExtensions Files accepted by the open dialog & drag and drop
+var romExtensions = slices.Concat(emulator.ROMExtensions, emulator.ArchiveExtensions)
+
+// isROMFile Does path look like a ROM file
+func isROMFile(path string) bool {
+	return slices.Contains(romExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// recentROMs Get recently opened ROMs, most recent first
+func recentROMs(prefs fyne.Preferences) []string {
+	return prefs.StringList(recentROMsPreference)
+}
+
+// addRecentROM Move path at the top of the recent ROMs
+func addRecentROM(prefs fyne.Preferences, path string) {
+	recent := slices.DeleteFunc(recentROMs(prefs), func(p string) bool {
+		return p == path
+	})
+	recent = append([]string{path}, recent...)
+	if len(recent) > maxRecentROMs {
+		recent = recent[:maxRecentROMs]
+	}
+	prefs.SetStringList(recentROMsPreference, recent)
+}
+
+// showOpenROMDialog Ask for a ROM file, open is called with its path
+func showOpenROMDialog(w fyne.Window, open func(path string)) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if reader == nil {
+			// Cancelled
+			return
+		}
+		path := reader.URI().Path()
+		_ = reader.Close()
+		open(path)
+	}, w)
+	d.SetFilter(storage.NewExtensionFileFilter(romExtensions))
+	d.Show()
+}
+
+// readROM Read the ROM at path, asking which one to open when a zip archive holds several.
+// patch is applied to it, the patch named after the ROM when empty (see emulator.PatchROM).
+// open is called with the ROM once read, errors are reported in a dialog.
+func readROM(w fyne.Window, path string, patch string, open func(rom emulator.ROMFile)) {
+	read := func(read func() (emulator.ROMFile, error)) {
+		rom, err := read()
+		if err == nil {
+			rom, err = emulator.PatchROM(rom, patch)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error loading ROM %s: %w", path, err), w)
+			return
+		}
+		open(rom)
+	}
+
+	if strings.ToLower(filepath.Ext(path)) != ".zip" {
+		read(func() (emulator.ROMFile, error) { return emulator.ReadROM(path) })
+		return
+	}
+
+	entries, err := emulator.ZipROMEntries(path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	if len(entries) == 1 {
+		read(func() (emulator.ROMFile, error) { return emulator.ReadZipROM(path, entries[0]) })
+		return
+	}
+
+	choice := widget.NewRadioGroup(entries, nil)
+	choice.SetSelected(entries[0])
+	choice.Required = true
+	dialog.ShowCustomConfirm("Choose a ROM in "+filepath.Base(path), "Open", "Cancel", container.NewVScroll(choice), func(ok bool) {
+		if ok {
+			read(func() (emulator.ROMFile, error) { return emulator.ReadZipROM(path, choice.Selected) })
+		}
+	}, w)
+}
+
+// recentROMsMenu Build the "Open Recent" menu items
+func recentROMsMenu(prefs fyne.Preferences, open func(path string)) []*fyne.MenuItem {
+	var items []*fyne.MenuItem
+	for _, path := range recentROMs(prefs) {
+		items = append(items, fyne.NewMenuItem(filepath.Base(path), func() {
+			open(path)
+		}))
+	}
+	if len(items) == 0 {
+		empty := fyne.NewMenuItem("No recent ROM", nil)
+		empty.Disabled = true
+		items = append(items, empty)
+	}
+	return items
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"image"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// runner Emulation goroutine, running whole frames at the hardware rate (59.73 Hz on a DMG) times the speed setting.
+// The DMG is owned by the runner : the UI must go through Do to access it.
+type runner struct {
+	mu             sync.Mutex
+	dmg            *emulator.DMG
+	running        bool
+	speed          emulator.Speed
+	turboSpeed     emulator.Speed // Speed while turbo is on
+	turbo          atomic.Bool
+	rewinding      atomic.Bool
+	keys           atomic.Uint32 // Buttons pressed on the keyboard
+	pad            atomic.Uint32 // Buttons pressed on gamepads
+	rewindInterval int
+	frameDuration  time.Duration // At normal speed, also the shortest delay between two frames shown
+	wake           chan struct{}
+	// onFrame Called from the emulation goroutine after each frame shown, with a copy of the DMG frame buffer the UI owns
+	onFrame func(frame image.Image, cpu emulator.Gbz80)
+	// onBreak Called from the emulation goroutine when a breakpoint pauses emulation, when set
+	onBreak func(hit emulator.BreakHit)
+}
+
+// newRunner Create a runner for dmg, paused
+func newRunner(dmg *emulator.DMG, rewindInterval int, onFrame func(frame image.Image, cpu emulator.Gbz80)) *runner {
+	return &runner{
+		dmg:            dmg,
+		speed:          emulator.SpeedNormal,
+		turboSpeed:     emulator.SpeedUnlimited,
+		rewindInterval: max(rewindInterval, 1),
+		frameDuration:  time.Second * emulator.CyclesPerFrame / time.Duration(dmg.Model.ClockSpeed()),
+		wake:           make(chan struct{}, 1),
+		onFrame:        onFrame,
+	}
+}
+
+// Do Run fn with exclusive access to the DMG
+func (r *runner) Do(fn func(dmg *emulator.DMG)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(r.dmg)
+}
+
+// Running Is emulation running
+func (r *runner) Running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// Run Resume emulation
+func (r *runner) Run() {
+	r.mu.Lock()
+	r.running = true
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Pause Stop emulation at the end of the current frame
+func (r *runner) Pause() {
+	r.mu.Lock()
+	r.running = false
+	r.mu.Unlock()
+}
+
+// SetSpeed Set the emulation speed, and the one used while turbo is on
+func (r *runner) SetSpeed(speed, turboSpeed emulator.Speed) {
+	r.mu.Lock()
+	r.speed = speed
+	r.turboSpeed = turboSpeed
+	r.mu.Unlock()
+}
+
+// SetTurbo Switch turbo on or off, while the turbo key is held
+func (r *runner) SetTurbo(turbo bool) {
+	r.turbo.Store(turbo)
+}
+
+// SetRewinding Start or stop playing backwards, emulation being paused meanwhile
+func (r *runner) SetRewinding(rewinding bool) {
+	r.rewinding.Store(rewinding)
+	r.signal()
+}
+
+// SetKeys Set the buttons pressed on the keyboard
+func (r *runner) SetKeys(buttons emulator.Button) {
+	r.keys.Store(uint32(buttons))
+}
+
+// SetGamepad Set the buttons pressed on gamepads
+func (r *runner) SetGamepad(buttons emulator.Button) {
+	r.pad.Store(uint32(buttons))
+}
+
+// signal Wake the emulation goroutine up if it is idle
+func (r *runner) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start Start the emulation goroutine
+func (r *runner) Start() {
+	go r.loop()
+}
+
+func (r *runner) loop() {
+	next := time.Now()
+	var shown time.Time
+	for {
+		rewinding := r.rewinding.Load()
+
+		r.mu.Lock()
+		active := r.running || rewinding
+		var frame image.Image
+		var cpu emulator.Gbz80
+		frameDuration := r.speed.FrameDuration(r.dmg.Model)
+		if r.turbo.Load() {
+			frameDuration = r.turboSpeed.FrameDuration(r.dmg.Model)
+		}
+		frames := 1
+		var hit *emulator.BreakHit
+		// Above normal speed, frames are skipped so that the UI isn't shown more frames than the hardware rate
+		show := time.Since(shown) >= r.frameDuration-time.Millisecond
+		if rewinding {
+			// Play backwards one snapshot at a time, at the speed it was recorded
+			if r.dmg.Rewind(r.rewindInterval) == nil {
+				frame = r.dmg.Snapshot()
+			}
+			frames = r.rewindInterval
+			frameDuration = r.frameDuration
+		} else if active {
+			r.dmg.SetJoypad(emulator.Button(r.keys.Load() | r.pad.Load()))
+			r.dmg.RunFrame()
+			if hit = r.dmg.BreakHit(); hit != nil {
+				r.running = false
+			}
+			if show || hit != nil {
+				frame = r.dmg.Snapshot()
+			}
+		}
+		cpu = *r.dmg.Gbz80
+		r.mu.Unlock()
+
+		if !active {
+			<-r.wake
+			next = time.Now()
+			continue
+		}
+		if hit != nil {
+			r.onFrame(frame, cpu)
+			if r.onBreak != nil {
+				r.onBreak(*hit)
+			}
+			continue
+		}
+
+		if frame != nil && show {
+			shown = time.Now()
+			r.onFrame(frame, cpu)
+		}
+
+		// Pace frames on the speed, without trying to catch up after a long stall
+		next = next.Add(frameDuration * time.Duration(frames))
+		delay := time.Until(next)
+		if delay > 0 {
+			time.Sleep(delay)
+		} else if delay < -10*max(frameDuration, r.frameDuration) {
+			next = time.Now()
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+const (
+	speedPreference      = "emulation.speed"
+	turboSpeedPreference = "emulation.turboSpeed"
+	turboKey             = fyne.KeyTab
+)
+
+// speedMenu "Speed" menu : emulation speed, and the one used while the turbo key is held.
+// Speeds given on the command line take over the ones saved in preferences.
+type speedMenu struct {
+	prefs      fyne.Preferences
+	emu        *runner
+	speed      emulator.Speed
+	turboSpeed emulator.Speed
+	speedItems []*fyne.MenuItem
+	turboItems []*fyne.MenuItem
+	onMenu     func() // Called when menu items need a refresh
+}
+
+// newSpeedMenu Create the menu, applying the speeds to emu
+func newSpeedMenu(prefs fyne.Preferences, emu *runner, speed, turboSpeed *emulator.Speed) *speedMenu {
+	m := &speedMenu{
+		prefs:      prefs,
+		emu:        emu,
+		speed:      emulator.Speed(prefs.FloatWithFallback(speedPreference, float64(emulator.SpeedNormal))),
+		turboSpeed: emulator.Speed(prefs.FloatWithFallback(turboSpeedPreference, float64(emulator.SpeedUnlimited))),
+	}
+	if speed != nil {
+		m.speed = *speed
+	}
+	if turboSpeed != nil {
+		m.turboSpeed = *turboSpeed
+	}
+	emu.SetSpeed(m.speed, m.turboSpeed)
+	return m
+}
+
+// SetSpeed Change the emulation speed
+func (m *speedMenu) SetSpeed(speed emulator.Speed) {
+	m.speed = speed
+	m.prefs.SetFloat(speedPreference, float64(speed))
+	m.emu.SetSpeed(m.speed, m.turboSpeed)
+	m.refresh()
+}
+
+// SetTurboSpeed Change the speed used while the turbo key is held
+func (m *speedMenu) SetTurboSpeed(speed emulator.Speed) {
+	m.turboSpeed = speed
+	m.prefs.SetFloat(turboSpeedPreference, float64(speed))
+	m.emu.SetSpeed(m.speed, m.turboSpeed)
+	m.refresh()
+}
+
+// menu Build the "Speed" menu
+func (m *speedMenu) menu() *fyne.Menu {
+	m.speedItems, m.turboItems = nil, nil
+	for _, s := range emulator.Speeds {
+		m.speedItems = append(m.speedItems, fyne.NewMenuItem(speedLabel(s), func() {
+			m.SetSpeed(s)
+		}))
+		if s > emulator.SpeedNormal || s == emulator.SpeedUnlimited {
+			m.turboItems = append(m.turboItems, fyne.NewMenuItem(speedLabel(s), func() {
+				m.SetTurboSpeed(s)
+			}))
+		}
+	}
+	turbo := fyne.NewMenuItem("Turbo (hold Tab)", nil)
+	turbo.ChildMenu = fyne.NewMenu("", m.turboItems...)
+
+	items := append([]*fyne.MenuItem{}, m.speedItems...)
+	items = append(items, fyne.NewMenuItemSeparator(), turbo)
+	m.refresh()
+	return fyne.NewMenu("Speed", items...)
+}
+
+// speedLabel Menu label of s
+func speedLabel(s emulator.Speed) string {
+	if s == emulator.SpeedUnlimited {
+		return "Unlimited"
+	}
+	return s.String()
+}
+
+func (m *speedMenu) refresh() {
+	for i, item := range m.speedItems {
+		item.Checked = emulator.Speeds[i] == m.speed
+	}
+	for _, item := range m.turboItems {
+		item.Checked = item.Label == speedLabel(m.turboSpeed)
+	}
+	if m.onMenu != nil {
+		m.onMenu()
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"khopa.github.io/gogbemulator/emulator"
+)
+
+// stateExtension Extension of quick save states, saved next to the ROM & named after it
+const stateExtension = ".state"
+
+// stateMenu Quick save & load of the emulator state
+type stateMenu struct {
+	window fyne.Window
+	emu    *runner
+	show   func(dmg *emulator.DMG) // Show the frame once a state is loaded, called with exclusive access to the DMG
+	loaded func()                  // Called once a state is loaded
+	save   *fyne.MenuItem
+	load   *fyne.MenuItem
+}
+
+// newStateMenu Create the menu items, disabled until a ROM is loaded
+func newStateMenu(w fyne.Window, emu *runner, show func(dmg *emulator.DMG), loaded func()) *stateMenu {
+	m := &stateMenu{window: w, emu: emu, show: show, loaded: loaded}
+	m.save = fyne.NewMenuItem("Save State", m.Save)
+	m.load = fyne.NewMenuItem("Load State", m.Load)
+	m.SetEnabled(false)
+	return m
+}
+
+// SetEnabled Enable the items, once a ROM is loaded
+func (m *stateMenu) SetEnabled(enabled bool) {
+	m.save.Disabled = !enabled
+	m.load.Disabled = !enabled
+}
+
+// Save Quick save the state
+func (m *stateMenu) Save() {
+	var path string
+	var err error
+	m.emu.Do(func(dmg *emulator.DMG) {
+		path = dmg.SavePath(stateExtension)
+		err = writeStateFile(dmg, path)
+	})
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error saving state %s: %w", path, err), m.window)
+	}
+}
+
+// Load Restore the quick saved state
+func (m *stateMenu) Load() {
+	var path string
+	var err error
+	m.emu.Do(func(dmg *emulator.DMG) {
+		path = dmg.SavePath(stateExtension)
+		err = readStateFile(dmg, path)
+		if err == nil {
+			m.show(dmg)
+		}
+	})
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error loading state %s: %w", path, err), m.window)
+		return
+	}
+	m.loaded()
+}
+
+// writeStateFile Save the state of dmg to path
+func writeStateFile(dmg *emulator.DMG, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := dmg.SaveState(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readStateFile Restore the state of dmg from path
+func readStateFile(dmg *emulator.DMG, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return dmg.LoadState(f)
+}